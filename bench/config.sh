@@ -78,9 +78,26 @@ RESULTS_DIR="./bench/results"
 # Date format for result file names
 DATE_FORMAT="%Y%m%d_%H%M%S"
 
+# Append-only benchmark history (one JSON object per line)
+# Every run is stamped with git commit, Go version, CPU model and corpus hash
+HISTORY_FILE="${HISTORY_FILE:-${RESULTS_DIR}/history.jsonl}"
+
 #------------------------------------------------------------------------------
 # Cold run settings (cache clearing)
 #------------------------------------------------------------------------------
+# How cold runs evict the page cache:
+#   fadvise - drop only the corpus files with posix_fadvise (no sudo, Linux only)
+#   command - run CLEAR_CACHE_CMD (drops all caches, usually requires sudo)
+if [[ "$(uname)" == "Darwin" ]]; then
+    CLEAR_CACHE_METHOD="${CLEAR_CACHE_METHOD:-command}"
+else
+    CLEAR_CACHE_METHOD="${CLEAR_CACHE_METHOD:-fadvise}"
+fi
+
+# Eviction helper used by the fadvise method
+# Built automatically by run.sh with `go build -o bin/bench-evict ./bench/evict`
+EVICT_BIN="${EVICT_BIN:-./bin/bench-evict}"
+
 # Command to clear page cache (requires sudo on Linux)
 # On Linux: sync && echo 3 | sudo tee /proc/sys/vm/drop_caches
 # On macOS: sudo purge
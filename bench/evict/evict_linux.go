@@ -0,0 +1,68 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const fadvDontNeed = 4 // POSIX_FADV_DONTNEED
+
+// evictFile asks the kernel to drop the cached pages of path and then counts
+// how many of them are still resident according to mincore(2).
+func evictFile(path string) (size, resident, pages int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size = info.Size()
+
+	// Dirty pages are not dropped by DONTNEED, so flush freshly generated
+	// corpus files first.
+	if err := f.Sync(); err != nil {
+		return size, 0, 0, err
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, fadvDontNeed, 0, 0)
+	if errno != 0 {
+		return size, 0, 0, os.NewSyscallError("fadvise64", errno)
+	}
+
+	if size == 0 {
+		return size, 0, 0, nil
+	}
+	resident, pages, err = residentPages(f, size)
+	return size, resident, pages, err
+}
+
+// residentPages maps f without touching it and reports how many of its pages
+// are in the page cache.
+func residentPages(f *os.File, size int64) (resident, pages int64, err error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return 0, 0, os.NewSyscallError("mmap", err)
+	}
+	defer syscall.Munmap(data)
+
+	pageSize := int64(os.Getpagesize())
+	pages = (size + pageSize - 1) / pageSize
+	vec := make([]byte, pages)
+	_, _, errno := syscall.Syscall(syscall.SYS_MINCORE,
+		uintptr(unsafe.Pointer(&data[0])), uintptr(size), uintptr(unsafe.Pointer(&vec[0])))
+	if errno != 0 {
+		return 0, pages, os.NewSyscallError("mincore", errno)
+	}
+	for _, v := range vec {
+		if v&1 != 0 {
+			resident++
+		}
+	}
+	return resident, pages, nil
+}
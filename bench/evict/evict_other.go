@@ -0,0 +1,12 @@
+//go:build !(linux && (amd64 || arm64))
+
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+func evictFile(path string) (size, resident, pages int64, err error) {
+	return 0, 0, 0, errors.New("page cache eviction is not supported on " + runtime.GOOS + "/" + runtime.GOARCH)
+}
@@ -0,0 +1,66 @@
+// Command evict drops benchmark corpus files from the OS page cache without
+// root privileges, so that cold benchmarks can run on shared machines.
+//
+// Usage: go build -o bin/bench-evict ./bench/evict
+//
+//	bench-evict [-q] [-check] <path>...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	quiet := flag.Bool("q", false, "only print warnings and errors")
+	check := flag.Bool("check", false, "exit with status 2 if any page is still resident after eviction")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bench-evict [-q] [-check] <path>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var files, bytes, resident, total int64
+	for _, root := range flag.Args() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			size, res, pages, err := evictFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			files++
+			bytes += size
+			resident += res
+			total += pages
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error evicting file:", err)
+			os.Exit(1)
+		}
+	}
+
+	if !*quiet {
+		fmt.Printf("evicted %d files (%.1f MB), %d/%d pages still resident\n",
+			files, float64(bytes)/(1<<20), resident, total)
+	}
+	if resident > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d/%d pages still resident after eviction\n", resident, total)
+		if *check {
+			os.Exit(2)
+		}
+	}
+}
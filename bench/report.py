@@ -14,10 +14,14 @@ Options:
     --compare FILE1 FILE2 Compare two specific result files
     --json                Output report in JSON format
     --csv                 Output report in CSV format
+    --trend               Report mean times over commits from the history file
+    --history PATH        History file for --trend (default: ./bench/results/history.jsonl)
+    --format FORMAT       Trend report format: markdown or html (default: markdown)
     -h, --help            Show this help message
 """
 
 import argparse
+import html
 import json
 
 import sys
@@ -288,6 +292,186 @@ def print_json_report(runs: list[BenchmarkRun]):
     print(json.dumps(output, indent=2))
 
 
+@dataclass
+class HistoryEntry:
+    """Represents one line of the append-only benchmark history."""
+    corpus: str
+    pattern_class: str
+    pattern: str
+    cold_run: bool
+    cpu_model: str
+    git_commit: str
+    git_commit_date: str
+    git_dirty: bool
+    go_version: str
+    corpus_hash: str
+    timestamp: str
+    means: dict[str, float]
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "HistoryEntry":
+        """Create from a history JSON object."""
+        return cls(
+            corpus=data.get("corpus", data.get("benchmark_name", "unknown")),
+            pattern_class=data.get("pattern_class", "unknown"),
+            pattern=data.get("pattern", "unknown"),
+            cold_run=data.get("cold_run", False),
+            cpu_model=data.get("cpu_model", "unknown"),
+            git_commit=data.get("git_commit", "unknown"),
+            git_commit_date=data.get("git_commit_date", "unknown"),
+            git_dirty=data.get("git_dirty", False),
+            go_version=data.get("go_version", "unknown"),
+            corpus_hash=data.get("corpus_hash", ""),
+            timestamp=data.get("timestamp", "unknown"),
+            means={
+                get_tool_name(r.get("tool", "unknown")): r.get("mean", 0)
+                for r in data.get("results", [])
+            },
+        )
+
+
+@dataclass
+class TrendRow:
+    """Aggregated results of all runs of one benchmark at one commit."""
+    commit: str
+    commit_date: str
+    dirty: bool
+    go_version: str
+    corpus_hash: str
+    runs: int
+    means: dict[str, float]
+
+
+def load_history(history_file: Path) -> list[HistoryEntry]:
+    """Load all entries from the history file, skipping malformed lines."""
+    entries = []
+    try:
+        with open(history_file, "r") as f:
+            for lineno, line in enumerate(f, 1):
+                line = line.strip()
+                if not line:
+                    continue
+                try:
+                    obj = json.loads(line)
+                except json.JSONDecodeError as e:
+                    print(f"Warning: {history_file}:{lineno}: {e}", file=sys.stderr)
+                    continue
+                if not isinstance(obj, dict):
+                    print(f"Warning: {history_file}:{lineno}: not a JSON object", file=sys.stderr)
+                    continue
+                entries.append(HistoryEntry.from_dict(obj))
+    except IOError as e:
+        print(f"Error: Failed to read history {history_file}: {e}", file=sys.stderr)
+    return entries
+
+
+def build_trends(entries: list[HistoryEntry]) -> dict[tuple, list[TrendRow]]:
+    """Group history entries by benchmark and aggregate them per commit.
+
+    Groups are keyed by (corpus, pattern class, pattern, cache mode, CPU) since
+    timings from different machines are not comparable. Within a group, rows
+    are ordered by commit date and use the median of the per-run means.
+    """
+    groups: dict[tuple, dict[str, list[HistoryEntry]]] = {}
+    for entry in entries:
+        cache_mode = "cold" if entry.cold_run else "warm"
+        key = (entry.corpus, entry.pattern_class, entry.pattern, cache_mode, entry.cpu_model)
+        commit = entry.git_commit + ("+dirty" if entry.git_dirty else "")
+        groups.setdefault(key, {}).setdefault(commit, []).append(entry)
+
+    trends = {}
+    for key, by_commit in sorted(groups.items()):
+        rows = []
+        for commit, runs in by_commit.items():
+            tools = {tool for run in runs for tool in run.means}
+            rows.append(TrendRow(
+                commit=runs[-1].git_commit,
+                commit_date=runs[-1].git_commit_date,
+                dirty=runs[-1].git_dirty,
+                go_version=runs[-1].go_version,
+                corpus_hash=runs[-1].corpus_hash,
+                runs=len(runs),
+                means={
+                    tool: median([run.means[tool] for run in runs if tool in run.means])
+                    for tool in tools
+                },
+            ))
+        # Python's sort is stable, so commits with equal dates keep history order
+        rows.sort(key=lambda r: r.commit_date)
+        trends[key] = rows
+    return trends
+
+
+def trend_table(rows: list[TrendRow]) -> tuple[list[str], list[list[str]]]:
+    """Build header and cells of a trend table for one benchmark group."""
+    tools = sorted({tool for row in rows for tool in row.means})
+    header = ["Commit", "Date", "Go", "Corpus", "Runs"] + tools + ["mygrep change"]
+
+    cells = []
+    prev_mygrep = None
+    prev_hash = None
+    for row in rows:
+        commit = row.commit[:10] + ("+dirty" if row.dirty else "")
+        corpus_hash = row.corpus_hash[:8]
+        # Timings against a regenerated corpus are not directly comparable
+        if prev_hash is not None and row.corpus_hash != prev_hash:
+            corpus_hash += " (changed)"
+        prev_hash = row.corpus_hash
+
+        line = [commit, row.commit_date[:10], row.go_version, corpus_hash, str(row.runs)]
+        for tool in tools:
+            line.append(format_time(row.means[tool]) if tool in row.means else "N/A")
+
+        mygrep = row.means.get("mygrep")
+        if mygrep is None or prev_mygrep is None or prev_mygrep <= 0:
+            line.append("")
+        else:
+            line.append(f"{(mygrep - prev_mygrep) / prev_mygrep * 100:+.1f}%")
+        if mygrep is not None:
+            prev_mygrep = mygrep
+        cells.append(line)
+    return header, cells
+
+
+def print_trend_markdown(trends: dict[tuple, list[TrendRow]]):
+    """Print trend tables in Markdown format."""
+    print("# mygrep Benchmark Trends")
+    print()
+    print(f"Generated: {datetime.now().strftime('%Y-%m-%d %H:%M:%S')}")
+    for (corpus, pattern_class, pattern, cache_mode, cpu_model), rows in trends.items():
+        header, cells = trend_table(rows)
+        print()
+        print(f"## {corpus} / {pattern_class} ({cache_mode})")
+        print()
+        print(f"Pattern: `{pattern}`, CPU: {cpu_model}")
+        print()
+        print("| " + " | ".join(header) + " |")
+        print("|" + "|".join("---" for _ in header) + "|")
+        for line in cells:
+            print("| " + " | ".join(line) + " |")
+
+
+def print_trend_html(trends: dict[tuple, list[TrendRow]]):
+    """Print trend tables as a standalone HTML page."""
+    esc = html.escape
+    print("<!DOCTYPE html>")
+    print("<html><head><meta charset=\"utf-8\"><title>mygrep Benchmark Trends</title>")
+    print("<style>table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:2px 8px;text-align:right}</style>")
+    print("</head><body>")
+    print("<h1>mygrep Benchmark Trends</h1>")
+    print(f"<p>Generated: {datetime.now().strftime('%Y-%m-%d %H:%M:%S')}</p>")
+    for (corpus, pattern_class, pattern, cache_mode, cpu_model), rows in trends.items():
+        header, cells = trend_table(rows)
+        print(f"<h2>{esc(corpus)} / {esc(pattern_class)} ({esc(cache_mode)})</h2>")
+        print(f"<p>Pattern: <code>{esc(pattern)}</code>, CPU: {esc(cpu_model)}</p>")
+        print("<table>")
+        print("<tr>" + "".join(f"<th>{esc(h)}</th>" for h in header) + "</tr>")
+        for line in cells:
+            print("<tr>" + "".join(f"<td>{esc(c)}</td>" for c in line) + "</tr>")
+        print("</table>")
+    print("</body></html>")
+
+
 def find_result_files(results_dir: Path, latest_only: bool = False) -> list[Path]:
     """Find all JSON result files in the results directory."""
     if not results_dir.exists():
@@ -358,6 +542,23 @@ def main():
         action="store_true",
         help="Output report in CSV format"
     )
+    parser.add_argument(
+        "--trend",
+        action="store_true",
+        help="Report mean times over commits from the history file"
+    )
+    parser.add_argument(
+        "--history",
+        type=Path,
+        default=Path("./bench/results/history.jsonl"),
+        help="History file for --trend"
+    )
+    parser.add_argument(
+        "--format",
+        choices=["markdown", "html"],
+        default="markdown",
+        help="Trend report format"
+    )
     parser.add_argument(
         "--verbose", "-v",
         action="store_true",
@@ -366,6 +567,18 @@ def main():
 
     args = parser.parse_args()
 
+    if args.trend:
+        entries = load_history(args.history)
+        if not entries:
+            print("No benchmark history to process.", file=sys.stderr)
+            sys.exit(1)
+        trends = build_trends(entries)
+        if args.format == "html":
+            print_trend_html(trends)
+        else:
+            print_trend_markdown(trends)
+        return
+
     # Find result files
     if args.compare:
         result_files = [Path(f) for f in args.compare]
@@ -18,6 +18,13 @@ VERBOSE=false
 DRY_RUN=false
 SKIP_BINARY_CHECK=false
 
+# Environment metadata (filled in by collect_environment)
+GIT_COMMIT="unknown"
+GIT_COMMIT_DATE="unknown"
+GIT_DIRTY=false
+GO_VERSION="unknown"
+CPU_MODEL="unknown"
+
 #------------------------------------------------------------------------------
 # Helper functions
 #------------------------------------------------------------------------------
@@ -97,10 +104,29 @@ check_prerequisites() {
         exit 1
     fi
 
+    # Build the page cache eviction helper for unprivileged cold runs
+    if [[ "${RUN_COLD}" == true && "${CLEAR_CACHE_METHOD}" == "fadvise" ]]; then
+        if [[ ! -x "${EVICT_BIN}" ]]; then
+            if ! check_command "go" "go"; then
+                log_error "go not found; cannot build ${EVICT_BIN}"
+                log_error "Set CLEAR_CACHE_METHOD=command to use CLEAR_CACHE_CMD instead."
+                exit 1
+            fi
+            log_info "Building page cache eviction helper: ${EVICT_BIN}"
+            go build -o "${EVICT_BIN}" ./bench/evict || {
+                log_error "Failed to build ${EVICT_BIN}"
+                exit 1
+            }
+        fi
+    fi
+
     log_info "Prerequisites check passed"
     log_verbose "  mygrep: ${MYGREP_BIN}"
     log_verbose "  ripgrep: ${RG_AVAILABLE} (${RG_BIN})"
     log_verbose "  GNU grep: ${GREP_AVAILABLE} (${GREP_BIN})"
+    if [[ "${RUN_COLD}" == true ]]; then
+        log_verbose "  cache clear: ${CLEAR_CACHE_METHOD}"
+    fi
 }
 
 check_corpus() {
@@ -156,16 +182,73 @@ get_search_pattern() {
     esac
 }
 
+# Print the command that evicts the given target from the page cache
+clear_cache_cmd() {
+    local target="$1"
+    case "${CLEAR_CACHE_METHOD}" in
+        fadvise) echo "${EVICT_BIN} -q '${target}'" ;;
+        *)       echo "${CLEAR_CACHE_CMD}" ;;
+    esac
+}
+
 clear_cache() {
+    local target="$1"
     if [[ "${RUN_COLD}" == true ]]; then
         log_verbose "Clearing page cache..."
+        if [[ "${CLEAR_CACHE_METHOD}" == "fadvise" ]]; then
+            # -check verifies with mincore that no corpus page stayed resident
+            "${EVICT_BIN}" -check "${target}" || {
+                log_warn "Corpus pages are still cached. Cold benchmarks may not be accurate."
+                log_warn "Files mapped by other processes cannot be evicted."
+            }
+            return
+        fi
         eval "${CLEAR_CACHE_CMD}" 2>/dev/null || {
             log_warn "Failed to clear cache. Cold benchmarks may not be accurate."
-            log_warn "Try running with sudo or skip cold benchmarks."
+            log_warn "Try running with sudo, set CLEAR_CACHE_METHOD=fadvise, or skip cold benchmarks."
         }
     fi
 }
 
+collect_environment() {
+    if git rev-parse --git-dir &> /dev/null; then
+        GIT_COMMIT=$(git rev-parse HEAD)
+        GIT_COMMIT_DATE=$(git log -1 --format=%cI HEAD)
+        if [[ -n "$(git status --porcelain --untracked-files=no)" ]]; then
+            GIT_DIRTY=true
+        fi
+    fi
+
+    # Prefer the toolchain the binary was built with over the installed one
+    if check_command "go" "go"; then
+        GO_VERSION=$(go version "${MYGREP_BIN}" 2>/dev/null | awk '{print $2}')
+        if [[ -z "${GO_VERSION}" ]]; then
+            GO_VERSION=$(go env GOVERSION)
+        fi
+    fi
+
+    if [[ "$(uname)" == "Darwin" ]]; then
+        CPU_MODEL=$(sysctl -n machdep.cpu.brand_string 2>/dev/null || echo "unknown")
+    elif [[ -r /proc/cpuinfo ]]; then
+        CPU_MODEL=$(awk -F': ' '/^model name/ {print $2; exit}' /proc/cpuinfo)
+        CPU_MODEL="${CPU_MODEL:-unknown}"
+    fi
+
+    log_verbose "  commit: ${GIT_COMMIT} (dirty: ${GIT_DIRTY})"
+    log_verbose "  go: ${GO_VERSION}"
+    log_verbose "  cpu: ${CPU_MODEL}"
+}
+
+# Print a SHA-256 over the names and contents of all files under path
+get_corpus_hash() {
+    local path="$1"
+    local sha_cmd="sha256sum"
+    if ! check_command "sha256sum" "sha256sum"; then
+        sha_cmd="shasum -a 256"
+    fi
+    find "${path}" -type f -print0 | LC_ALL=C sort -z | xargs -0 ${sha_cmd} | ${sha_cmd} | cut -d' ' -f1
+}
+
 get_file_size_mb() {
     local path="$1"
     if [[ -d "${path}" ]]; then
@@ -210,7 +293,7 @@ run_benchmark_single_file() {
 
     # Add prepare command for cold runs
     if [[ "${RUN_COLD}" == true ]]; then
-        hyperfine_opts+=("--prepare" "${CLEAR_CACHE_CMD}")
+        hyperfine_opts+=("--prepare" "$(clear_cache_cmd "${file}")")
     fi
 
     # Build the command list
@@ -231,6 +314,8 @@ run_benchmark_single_file() {
         return
     fi
 
+    clear_cache "${file}"
+
     # Run hyperfine
     hyperfine "${hyperfine_opts[@]}" "${commands[@]}" || {
         log_warn "Benchmark failed or was interrupted"
@@ -279,7 +364,7 @@ run_benchmark_directory() {
     )
 
     if [[ "${RUN_COLD}" == true ]]; then
-        hyperfine_opts+=("--prepare" "${CLEAR_CACHE_CMD}")
+        hyperfine_opts+=("--prepare" "$(clear_cache_cmd "${dir}")")
     fi
 
     local commands=()
@@ -299,6 +384,8 @@ run_benchmark_directory() {
         return
     fi
 
+    clear_cache "${dir}"
+
     hyperfine "${hyperfine_opts[@]}" "${commands[@]}" || {
         log_warn "Benchmark failed or was interrupted"
         return 1
@@ -324,6 +411,27 @@ add_metadata_to_result() {
     if [[ "${RUN_COLD}" == "true" ]]; then
         cold_run_py="True"
     fi
+    local git_dirty_py="False"
+    if [[ "${GIT_DIRTY}" == "true" ]]; then
+        git_dirty_py="True"
+    fi
+
+    # Corpus name is the first directory below CORPUS_DIR (code_tree, log, binary)
+    local corpus="${target_path#"${CORPUS_DIR}"/}"
+    corpus="${corpus%%/*}"
+    local pattern_class="${PATTERN_TYPE}"
+    case "${corpus}" in
+        binary) pattern_class="embedded" ;;
+        *)
+            case "${PATTERN_TYPE}" in
+                common|rare|frequent) ;;
+                *) pattern_class="custom" ;;
+            esac
+            ;;
+    esac
+
+    local corpus_hash
+    corpus_hash=$(get_corpus_hash "${target_path}")
 
     # Add metadata using Python (more reliable JSON handling)
     python3 << EOF
@@ -343,13 +451,42 @@ try:
         "timestamp": "${TIMESTAMP}",
         "cold_run": ${cold_run_py},
         "warmup_runs": ${WARMUP_RUNS},
-        "bench_runs": ${BENCH_RUNS}
+        "bench_runs": ${BENCH_RUNS},
+        "corpus": "${corpus}",
+        "pattern_class": "${pattern_class}",
+        "corpus_hash": "${corpus_hash}",
+        "git_commit": "${GIT_COMMIT}",
+        "git_commit_date": "${GIT_COMMIT_DATE}",
+        "git_dirty": ${git_dirty_py},
+        "go_version": "${GO_VERSION}",
+        "cpu_model": "${CPU_MODEL}"
     }
 
     with open("${output_file}", "w") as f:
         json.dump(data, f, indent=2)
 except Exception as e:
     print(f"Warning: Failed to add metadata: {e}", file=sys.stderr)
+    sys.exit(0)
+
+# Append a summary line to the history file (never rewritten)
+try:
+    entry = dict(data["metadata"])
+    entry["result_file"] = "${output_file}"
+    entry["results"] = [
+        {
+            "tool": r.get("command", "unknown"),
+            "mean": r.get("mean"),
+            "stddev": r.get("stddev"),
+            "median": r.get("median"),
+            "min": r.get("min"),
+            "max": r.get("max"),
+        }
+        for r in data.get("results", [])
+    ]
+    with open("${HISTORY_FILE}", "a") as f:
+        f.write(json.dumps(entry, sort_keys=True) + "\n")
+except Exception as e:
+    print(f"Warning: Failed to append history: {e}", file=sys.stderr)
 EOF
 }
 
@@ -418,7 +555,7 @@ Run grep benchmarks comparing mygrep, ripgrep, and GNU grep.
 
 Options:
     --warm          Run warm benchmarks (OS cache active, default)
-    --cold          Run cold benchmarks (clear cache before each run)
+    --cold          Run cold benchmarks (evict corpus from page cache before each run)
     --corpus TYPE   Corpus type: all, code, log, binary (default: all)
     --pattern TYPE  Pattern type: common, rare, frequent, or custom string (default: common)
     --warmup N      Number of warmup runs (default: ${WARMUP_RUNS})
@@ -439,8 +576,11 @@ Environment variables:
     MYGREP_BIN      Path to mygrep binary (default: ./bin/mygrep)
     RG_BIN          Path to ripgrep binary (default: rg)
     GREP_BIN        Path to GNU grep binary (default: ggrep on macOS, grep on Linux)
+    CLEAR_CACHE_METHOD  Cold run cache clearing: fadvise or command (default: fadvise on Linux)
+    EVICT_BIN       Path to page cache eviction helper (default: ./bin/bench-evict)
 
 Results are saved to: ${RESULTS_DIR}/
+Each run is also appended to: ${HISTORY_FILE}
 EOF
 }
 
@@ -525,6 +665,8 @@ main() {
     # Create results directory
     mkdir -p "${RESULTS_DIR}"
 
+    collect_environment
+
     # Run benchmarks based on corpus type
     local start_time
     start_time=$(date +%s)
@@ -562,8 +704,11 @@ main() {
     log_info "Duration: ${duration} seconds"
     log_info "Results saved to: ${RESULTS_DIR}/"
     echo ""
+    log_info "History appended to: ${HISTORY_FILE}"
+    echo ""
     log_info "To generate a report, run:"
     log_info "  python3 ./bench/report.py"
+    log_info "  python3 ./bench/report.py --trend          # trend over commits"
     echo "============================================================"
 }
 
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baselineFile is the JSON written by --save-baseline and read by --baseline.
+type baselineFile struct {
+	Version int             `json:"version"`
+	Pattern string          `json:"pattern"`
+	Entries []baselineEntry `json:"entries"`
+}
+
+type baselineEntry struct {
+	Path     string `json:"path"`
+	Location string `json:"location,omitempty"` // the part of a document
+	Line     int    `json:"line"`
+	Text     string `json:"text"`
+}
+
+// baselineKey identifies a match independently of its line number, so that
+// edits elsewhere in a file do not make old matches look new.
+type baselineKey struct {
+	path     string
+	location string
+	text     string
+}
+
+func newBaselineKey(path, location, text string) baselineKey {
+	return baselineKey{
+		path:     baselinePath(nil, path),
+		location: location,
+		text:     strings.Join(strings.Fields(text), " "),
+	}
+}
+
+// baselinePath returns how path is recorded in a baseline: as the file
+// opened, with / as the separator.
+func baselinePath(root *sandbox, path string) string {
+	return filepath.ToSlash(filepath.Clean(root.resolve(path)))
+}
+
+// loadBaseline reads the baseline at path, which must have been saved for
+// the same pattern: matches of another pattern would be reported as fixed
+// and new.
+func loadBaseline(path, pattern string) (*baselineFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b baselineFile
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, msgInvalidBaseline.errorf(path, err)
+	}
+	if b.Version != 1 {
+		return nil, msgBaselineVersion.errorf(path, b.Version)
+	}
+	if b.Pattern != pattern {
+		return nil, msgBaselinePattern.errorf(path, b.Pattern, pattern)
+	}
+	return &b, nil
+}
+
+func saveBaseline(path, pattern string, entries []baselineEntry) error {
+	if entries == nil {
+		entries = []baselineEntry{}
+	}
+	data, err := json.MarshalIndent(&baselineFile{Version: 1, Pattern: pattern, Entries: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// baselinePrinter passes on only matches that are not in the baseline. Each
+// baseline entry absorbs one match, so a line that appears once more than
+// before is reported.
+type baselinePrinter struct {
+	printer
+	remaining map[baselineKey][]baselineEntry
+	passthru  bool // show known matches as plain lines instead of hiding them
+	hiding    bool // the last match was known, so hide its after-context
+	redact    *redactor
+	root      *sandbox
+	newCount  int
+}
+
+func newBaselinePrinter(p printer, b *baselineFile, passthru bool, redact *redactor, root *sandbox) *baselinePrinter {
+	bp := &baselinePrinter{
+		printer:   p,
+		remaining: make(map[baselineKey][]baselineEntry),
+		passthru:  passthru,
+		redact:    redact,
+		root:      root,
+	}
+	for _, e := range b.Entries {
+		k := newBaselineKey(e.Path, e.Location, e.Text)
+		bp.remaining[k] = append(bp.remaining[k], e)
+	}
+	return bp
+}
+
+func (p *baselinePrinter) printMatch(lm *lineMatch) error {
+	// Baselines saved with --redact hold redacted text.
+	k := newBaselineKey(baselinePath(p.root, lm.path), lm.location, p.redact.redact(lm.line))
+	if known := p.remaining[k]; len(known) > 0 {
+		p.remaining[k] = known[1:]
+		p.hiding = true
+		if p.passthru {
+			return p.printer.printContext(lm.path, contextLine{lineNum: lm.lineNum, text: lm.line, location: lm.location})
+		}
+		return nil
+	}
+	p.hiding = false
+	p.newCount++
+	return p.printer.printMatch(lm)
+}
+
+func (p *baselinePrinter) printContext(path string, c contextLine) error {
+	if p.hiding && !p.passthru {
+		return nil
+	}
+	return p.printer.printContext(path, c)
+}
+
+// writeFixed writes the baseline entries of the searched files that no
+// longer match, escaping control characters in paths if escape is set.
+func (p *baselinePrinter) writeFixed(w io.Writer, searched []string, escape bool) error {
+	inSearch := make(map[string]bool)
+	for _, path := range searched {
+		inSearch[baselinePath(p.root, path)] = true
+	}
+	var fixed []baselineEntry
+	for k, entries := range p.remaining {
+		if inSearch[k.path] {
+			fixed = append(fixed, entries...)
+		}
+	}
+	sort.Slice(fixed, func(i, j int) bool {
+		if fixed[i].Path != fixed[j].Path {
+			return fixed[i].Path < fixed[j].Path
+		}
+		if fixed[i].Location != fixed[j].Location {
+			return fixed[i].Location < fixed[j].Location
+		}
+		return fixed[i].Line < fixed[j].Line
+	})
+	for _, e := range fixed {
+		path := locatedPath(e.Path, e.Location)
+		if escape {
+			path = escapeControl(path)
+		}
+		if _, err := fmt.Fprintln(w, msgBaselineFixed.format(path, e.Line, e.Text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordingPrinter collects every match for --save-baseline.
+type recordingPrinter struct {
+	printer
+	redact  *redactor
+	root    *sandbox
+	entries []baselineEntry
+}
+
+func (p *recordingPrinter) printMatch(lm *lineMatch) error {
+	p.entries = append(p.entries, baselineEntry{
+		Path:     baselinePath(p.root, lm.path),
+		Location: lm.location,
+		Line:     lm.lineNum,
+		Text:     p.redact.redact(lm.line),
+	})
+	return p.printer.printMatch(lm)
+}
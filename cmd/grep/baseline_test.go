@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// collectPrinter records the lines it is given.
+type collectPrinter struct {
+	matches, context []string
+}
+
+func (p *collectPrinter) printMatch(lm *lineMatch) error {
+	p.matches = append(p.matches, lm.line)
+	return nil
+}
+
+func (p *collectPrinter) printContext(path string, c contextLine) error {
+	p.context = append(p.context, c.text)
+	return nil
+}
+
+func (p *collectPrinter) startFile()   {}
+func (p *collectPrinter) flush() error { return nil }
+
+func TestLoadBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	entries := []baselineEntry{{Path: "a.txt", Line: 1, Text: "TODO one"}}
+	if err := saveBaseline(path, "TODO", entries); err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadBaseline(path, "TODO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b.Entries, entries) {
+		t.Errorf("entries = %v, want %v", b.Entries, entries)
+	}
+	if _, err := loadBaseline(path, "FIXME"); err == nil {
+		t.Error("a baseline saved for another pattern was accepted")
+	}
+
+	for name, data := range map[string]string{
+		"not json":    "{",
+		"new version": `{"version": 2, "pattern": "TODO", "entries": []}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadBaseline(path, "TODO"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestBaselinePrinter(t *testing.T) {
+	b := &baselineFile{Version: 1, Pattern: "TODO", Entries: []baselineEntry{
+		{Path: "a.txt", Line: 1, Text: "TODO one"},
+		{Path: "a.txt", Line: 5, Text: "TODO   two"},
+		{Path: "a.txt", Line: 9, Text: "TODO gone"},
+		{Path: "b.txt", Line: 1, Text: "TODO other file"},
+		{Path: "d.docx", Location: "cell", Line: 1, Text: "TODO in a part"},
+	}}
+	out := &collectPrinter{}
+	bp := newBaselinePrinter(out, b, false, nil, nil)
+	for i, line := range []string{
+		"TODO one",   // known
+		"TODO new",   // new
+		"TODO two",   // known: spacing is ignored
+		"TODO one",   // one more than before
+		"TODO other", // new
+	} {
+		bp.printMatch(&lineMatch{path: "a.txt", lineNum: 20 + i, line: line})
+	}
+	if want := []string{"TODO new", "TODO one", "TODO other"}; !slices.Equal(out.matches, want) {
+		t.Errorf("new matches = %q, want %q", out.matches, want)
+	}
+	if bp.newCount != 3 {
+		t.Errorf("newCount = %d, want 3", bp.newCount)
+	}
+
+	var fixed bytes.Buffer
+	bp.writeFixed(&fixed, []string{"./a.txt", "d.docx"}, false)
+	want := "fixed: a.txt:9:TODO gone\nfixed: d.docx[cell]:1:TODO in a part\n"
+	if fixed.String() != want {
+		t.Errorf("fixed = %q, want %q", fixed.String(), want)
+	}
+
+	fixed.Reset()
+	b.Entries = []baselineEntry{{Path: "a\x1b[2Jb.txt", Line: 1, Text: "TODO"}}
+	bp = newBaselinePrinter(out, b, false, nil, nil)
+	bp.writeFixed(&fixed, []string{"a\x1b[2Jb.txt"}, true)
+	if want := "fixed: a\\x1b[2Jb.txt:1:TODO\n"; fixed.String() != want {
+		t.Errorf("escaped fixed = %q, want %q", fixed.String(), want)
+	}
+}
+
+func TestBaselinePrinterPassthru(t *testing.T) {
+	b := &baselineFile{Version: 1, Pattern: "x", Entries: []baselineEntry{{Path: "a.txt", Line: 1, Text: "x known"}}}
+	out := &collectPrinter{}
+	bp := newBaselinePrinter(out, b, true, nil, nil)
+	bp.printMatch(&lineMatch{path: "a.txt", lineNum: 1, line: "x known"})
+	bp.printMatch(&lineMatch{path: "a.txt", lineNum: 2, line: "x new"})
+	if !slices.Equal(out.context, []string{"x known"}) || !slices.Equal(out.matches, []string{"x new"}) {
+		t.Errorf("context = %q, matches = %q", out.context, out.matches)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+//go:generate python3 widthtables_gen.py
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// columnUnit is what --column and --max-columns count.
+type columnUnit int
+
+const (
+	unitByte      columnUnit = iota // bytes, as most tools count
+	unitCodepoint                   // Unicode code points
+	unitGrapheme                    // user-perceived characters (UAX #29)
+	unitDisplay                     // terminal cells: wide characters take two
+)
+
+func parseColumnUnit(name string) (columnUnit, error) {
+	switch name {
+	case "byte":
+		return unitByte, nil
+	case "codepoint":
+		return unitCodepoint, nil
+	case "grapheme":
+		return unitGrapheme, nil
+	case "display":
+		return unitDisplay, nil
+	}
+	return 0, msgInvalidColumnUnit.errorf(name)
+}
+
+// next returns the size in bytes of the first unit of s, and how many
+// columns it takes. Units never split a UTF-8 sequence.
+func (u columnUnit) next(s string) (size, width int) {
+	// Printable ASCII not followed by a combining rune is its own unit.
+	if len(s) > 0 && s[0] >= 0x20 && s[0] < 0x7f && (len(s) == 1 || s[1] < utf8.RuneSelf) {
+		return 1, 1
+	}
+	switch u {
+	case unitGrapheme:
+		return graphemeLen(s), 1
+	case unitDisplay:
+		size = graphemeLen(s)
+		return size, clusterWidth(s[:size])
+	}
+	_, size = utf8.DecodeRuneInString(s)
+	if u == unitByte {
+		return size, size
+	}
+	return size, 1
+}
+
+// cluster is like next, but its unit is always a whole grapheme cluster,
+// so that cutting between them never splits a flag or an accented letter.
+func (u columnUnit) cluster(s string) (size, width int) {
+	if u == unitGrapheme || u == unitDisplay {
+		return u.next(s)
+	}
+	size = graphemeLen(s)
+	return size, u.width(s[:size])
+}
+
+// width returns how many columns s takes.
+func (u columnUnit) width(s string) int {
+	if u == unitByte {
+		return len(s)
+	}
+	n := 0
+	for len(s) > 0 {
+		size, w := u.next(s)
+		s = s[size:]
+		n += w
+	}
+	return n
+}
+
+// column returns the 1-based column of byte offset off in line.
+func (u columnUnit) column(line string, off int) int {
+	return 1 + u.width(line[:off])
+}
+
+// truncateMarker is shown where --max-columns cut a line.
+const truncateMarker = "..."
+
+// truncate cuts text to at most limit columns, keeping the first match in
+// view with some text before it, and moves matches to the cut text. The
+// match is kept even if it leaves no room for the markers, which are then
+// left out, the one before the match first.
+func (u columnUnit) truncate(text string, matches [][2]int, limit int) (string, [][2]int) {
+	if u.width(text) <= limit {
+		return text, matches
+	}
+	marker := len(truncateMarker)
+
+	// Start a quarter of the room the match leaves before it, on a cluster
+	// boundary, unless the match already fits from the start of the line.
+	start, keep := 0, 0 // keep is where the text shown may end at the earliest
+	if len(matches) > 0 {
+		keep = matches[0][1]
+		if u.width(text[:keep]) > limit-marker {
+			var bounds []int // cluster boundaries up to the match
+			i := 0
+			for i < matches[0][0] {
+				bounds = append(bounds, i)
+				size, _ := u.cluster(text[i:])
+				i += size
+			}
+			start = matches[0][0]
+			if i > start { // the match starts inside a cluster
+				start = bounds[len(bounds)-1]
+			}
+			room := limit - 2*marker - u.width(text[start:keep])
+			for k := len(bounds) - 1; k >= 0 && u.width(text[bounds[k]:matches[0][0]]) <= room/4; k-- {
+				start = bounds[k]
+			}
+		}
+	}
+	lead := start > 0 && u.width(text[start:keep])+2*marker <= limit
+	room := limit
+	if lead {
+		room -= marker
+	}
+	end, used := start, 0
+	for end < len(text) {
+		size, w := u.cluster(text[end:])
+		if end >= keep && used+w > room-marker {
+			break
+		}
+		end += size
+		used += w
+	}
+	trail := end < len(text) && used+marker <= room
+
+	var b strings.Builder
+	shift := -start
+	if lead {
+		b.WriteString(truncateMarker)
+		shift += marker
+	}
+	b.WriteString(text[start:end])
+	if trail {
+		b.WriteString(truncateMarker)
+	}
+	var moved [][2]int
+	for _, m := range matches {
+		s, e := max(m[0], start), min(m[1], end)
+		if s < e {
+			moved = append(moved, [2]int{s + shift, e + shift})
+		}
+	}
+	return b.String(), moved
+}
+
+// displayWidth returns how many terminal cells s takes.
+func displayWidth(s string) int {
+	return unitDisplay.width(s)
+}
+
+// clusterWidth returns how many terminal cells a grapheme cluster takes:
+// two for an emoji, including ZWJ sequences and those made to show as
+// emoji by a variation selector, otherwise the sum of its runes' widths,
+// as terminals draw combining marks over the rune before them.
+func clusterWidth(cluster string) int {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	if unicode.Is(extendedPictographic, r) && runeWidth(r) == 2 || strings.ContainsRune(cluster, 0xFE0F) {
+		return 2
+	}
+	w := 0
+	for _, r := range cluster {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth returns how many terminal cells r takes on its own. Ambiguous
+// characters are counted as narrow, as most terminals show them.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf),
+		r >= 0x1160 && r <= 0x11ff, r >= 0xd7b0 && r <= 0xd7ff: // Hangul vowels and finals
+		return 0
+	case unicode.Is(eastAsianWide, r):
+		return 2
+	}
+	return 1
+}
+
+// graphemeLen returns the size in bytes of the first extended grapheme
+// cluster of s, following the rules of UAX #29.
+func graphemeLen(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return 0
+	}
+	prev := graphemeBreakOf(r)
+	pictographic := unicode.Is(extendedPictographic, r) // GB11: emoji Extend* ZWJ
+	regional := 0                                       // regional indicators so far
+	if prev == gbRegional {
+		regional = 1
+	}
+	i := size
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		cur := graphemeBreakOf(r)
+		curPictographic := unicode.Is(extendedPictographic, r)
+		if graphemeBoundary(prev, cur, pictographic && curPictographic, regional) {
+			break
+		}
+		switch {
+		case cur == gbRegional:
+			regional++
+		case cur != gbExtend && cur != gbZWJ:
+			pictographic = curPictographic
+		}
+		prev = cur
+		i += size
+	}
+	return i
+}
+
+// Grapheme_Cluster_Break property values.
+const (
+	gbOther = iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRegional
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+// graphemeBoundary reports whether a cluster breaks between runes of
+// break classes prev and cur. emojiZWJ is set when an emoji, Extend runes
+// and a ZWJ come before an emoji, and regional counts the regional
+// indicators before cur.
+func graphemeBoundary(prev, cur int, emojiZWJ bool, regional int) bool {
+	switch {
+	case prev == gbCR && cur == gbLF: // GB3
+		return false
+	case prev == gbCR, prev == gbLF, prev == gbControl: // GB4
+		return true
+	case cur == gbCR, cur == gbLF, cur == gbControl: // GB5
+		return true
+	case prev == gbL && (cur == gbL || cur == gbV || cur == gbLV || cur == gbLVT): // GB6
+		return false
+	case (prev == gbLV || prev == gbV) && (cur == gbV || cur == gbT): // GB7
+		return false
+	case (prev == gbLVT || prev == gbT) && cur == gbT: // GB8
+		return false
+	case cur == gbExtend, cur == gbZWJ, cur == gbSpacingMark, prev == gbPrepend: // GB9-9b
+		return false
+	case prev == gbZWJ && emojiZWJ: // GB11
+		return false
+	case prev == gbRegional && cur == gbRegional: // GB12-13
+		return regional%2 == 0
+	}
+	return true // GB999
+}
+
+func graphemeBreakOf(r rune) int {
+	switch {
+	case r == '\r':
+		return gbCR
+	case r == '\n':
+		return gbLF
+	case r == 0x200D:
+		return gbZWJ
+	case r == 0x200C, unicode.In(r, unicode.Mn, unicode.Me, unicode.Other_Grapheme_Extend),
+		r >= 0x1F3FB && r <= 0x1F3FF: // emoji modifiers
+		return gbExtend
+	case unicode.Is(unicode.Regional_Indicator, r):
+		return gbRegional
+	case unicode.Is(unicode.Prepended_Concatenation_Mark, r):
+		return gbPrepend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gbControl
+	case unicode.Is(unicode.Mc, r), r == 0x0E33, r == 0x0EB3:
+		return gbSpacingMark
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gbL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gbV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gbT
+	case r >= hangulSBase && r < hangulSBase+hangulSCount:
+		if (r-hangulSBase)%hangulTCount == 0 {
+			return gbLV
+		}
+		return gbLVT
+	}
+	return gbOther
+}
+
+// extendedPictographic approximates the Extended_Pictographic property of
+// emoji-data.txt, which the unicode package does not provide.
+var extendedPictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00a9, 0x00a9, 1}, {0x00ae, 0x00ae, 1}, {0x203c, 0x203c, 1}, {0x2049, 0x2049, 1},
+		{0x2122, 0x2122, 1}, {0x2139, 0x2139, 1}, {0x2194, 0x2199, 1}, {0x21a9, 0x21aa, 1},
+		{0x231a, 0x231b, 1}, {0x2328, 0x2328, 1}, {0x2388, 0x2388, 1}, {0x23cf, 0x23cf, 1},
+		{0x23e9, 0x23f3, 1}, {0x23f8, 0x23fa, 1}, {0x24c2, 0x24c2, 1}, {0x25aa, 0x25ab, 1},
+		{0x25b6, 0x25b6, 1}, {0x25c0, 0x25c0, 1}, {0x25fb, 0x25fe, 1}, {0x2600, 0x2605, 1},
+		{0x2607, 0x2612, 1}, {0x2614, 0x2685, 1}, {0x2690, 0x2705, 1}, {0x2708, 0x2712, 1},
+		{0x2714, 0x2714, 1}, {0x2716, 0x2716, 1}, {0x271d, 0x271d, 1}, {0x2721, 0x2721, 1},
+		{0x2728, 0x2728, 1}, {0x2733, 0x2734, 1}, {0x2744, 0x2744, 1}, {0x2747, 0x2747, 1},
+		{0x274c, 0x274c, 1}, {0x274e, 0x274e, 1}, {0x2753, 0x2755, 1}, {0x2757, 0x2757, 1},
+		{0x2763, 0x2767, 1}, {0x2795, 0x2797, 1}, {0x27a1, 0x27a1, 1}, {0x27b0, 0x27b0, 1},
+		{0x27bf, 0x27bf, 1}, {0x2934, 0x2935, 1}, {0x2b05, 0x2b07, 1}, {0x2b1b, 0x2b1c, 1},
+		{0x2b50, 0x2b50, 1}, {0x2b55, 0x2b55, 1}, {0x3030, 0x3030, 1}, {0x303d, 0x303d, 1},
+		{0x3297, 0x3297, 1}, {0x3299, 0x3299, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1f000, 0x1f0ff, 1}, {0x1f10d, 0x1f10f, 1}, {0x1f12f, 0x1f12f, 1}, {0x1f16c, 0x1f171, 1},
+		{0x1f17e, 0x1f17f, 1}, {0x1f18e, 0x1f18e, 1}, {0x1f191, 0x1f19a, 1}, {0x1f1ad, 0x1f1e5, 1},
+		{0x1f201, 0x1f20f, 1}, {0x1f21a, 0x1f21a, 1}, {0x1f22f, 0x1f22f, 1}, {0x1f232, 0x1f23a, 1},
+		{0x1f23c, 0x1f23f, 1}, {0x1f249, 0x1f3fa, 1}, {0x1f400, 0x1f53d, 1}, {0x1f546, 0x1f64f, 1},
+		{0x1f680, 0x1f6ff, 1}, {0x1f774, 0x1f77f, 1}, {0x1f7d5, 0x1f7ff, 1}, {0x1f80c, 0x1f80f, 1},
+		{0x1f848, 0x1f84f, 1}, {0x1f85a, 0x1f85f, 1}, {0x1f888, 0x1f88f, 1}, {0x1f8ae, 0x1f8ff, 1},
+		{0x1f90c, 0x1f93a, 1}, {0x1f93c, 0x1f945, 1}, {0x1f947, 0x1faff, 1}, {0x1fc00, 0x1fffd, 1},
+	},
+}
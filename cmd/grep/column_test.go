@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphemeLen(t *testing.T) {
+	tests := []struct{ s, first string }{
+		{"ab", "a"},
+		{"e\u0301x", "e\u0301"},                       // combining acute accent
+		{"🇯🇵🇺🇸", "🇯🇵"},                                // a flag is two regional indicators
+		{"🇯🇵🇺", "🇯🇵"},                                 // and an odd one out stands alone
+		{"\u1100\u1161\u11a8x", "\u1100\u1161\u11a8"}, // Hangul jamo make one syllable
+		{"한국", "한"},
+		{"👨\u200d👩\u200d👧!", "👨\u200d👩\u200d👧"}, // ZWJ sequence
+		{"👍🏽x", "👍🏽"},                           // skin tone modifier
+		{"\r\nx", "\r\n"},
+		{"\xffx", "\xff"},
+	}
+	for _, tt := range tests {
+		if got := tt.s[:graphemeLen(tt.s)]; got != tt.first {
+			t.Errorf("first cluster of %q = %q, want %q", tt.s, got, tt.first)
+		}
+	}
+}
+
+func TestColumnWidth(t *testing.T) {
+	tests := []struct {
+		s                                   string
+		bytes, codepoints, graphemes, cells int
+	}{
+		{"abc", 3, 3, 3, 3},
+		{"cafe\u0301", 6, 5, 4, 4},
+		{"日本語", 9, 3, 3, 6},
+		{"한국어", 9, 3, 3, 6},
+		{"\u1100\u1161\u11a8", 9, 3, 1, 2},
+		{"🇯🇵", 8, 2, 1, 2},
+		{"👨\u200d👩\u200d👧", 18, 5, 1, 2},
+		{"☺\ufe0f", 6, 2, 1, 2},
+		{"ｱｲｳ", 9, 3, 3, 3}, // halfwidth katakana
+	}
+	for _, tt := range tests {
+		for _, c := range []struct {
+			unit columnUnit
+			want int
+		}{
+			{unitByte, tt.bytes},
+			{unitCodepoint, tt.codepoints},
+			{unitGrapheme, tt.graphemes},
+			{unitDisplay, tt.cells},
+		} {
+			if got := c.unit.width(tt.s); got != c.want {
+				t.Errorf("unit %d: width(%q) = %d, want %d", c.unit, tt.s, got, c.want)
+			}
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	line := "日本🇯🇵 needle"
+	off := strings.Index(line, "needle")
+	for unit, want := range map[columnUnit]int{unitByte: 16, unitCodepoint: 6, unitGrapheme: 5, unitDisplay: 8} {
+		if got := unit.column(line, off); got != want {
+			t.Errorf("unit %d: column = %d, want %d", unit, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		unit    columnUnit
+		text    string
+		pattern string
+		limit   int
+		want    string
+	}{
+		{unitByte, "short", "short", 10, "short"},
+		{unitByte, "the needle is in the middle of a long line", "needle", 20, "the needle is in ..."},
+		{unitByte, "a long line with the needle near its end", "needle", 16, "... needle ne..."},
+		// Never cut a flag or a combined character in two, whatever is counted.
+		{unitByte, "ab🇯🇵cd needle", "ab", 9, "ab..."},
+		{unitByte, "ab🇯🇵cd needle", "ab", 13, "ab🇯🇵..."},
+		{unitCodepoint, "ab🇯🇵cd needle", "ab", 6, "ab..."},
+		{unitCodepoint, "ab🇯🇵cd needle", "ab", 7, "ab🇯🇵..."},
+		{unitByte, "ae\u0301iou needle", "a", 6, "a..."},
+		{unitGrapheme, "ab🇯🇵cd needle", "ab", 6, "ab🇯🇵..."},
+		{unitDisplay, "日本語のテキスト needle", "needle", 12, "...needle"},
+		// A match that starts inside a cluster shows the whole cluster.
+		{unitByte, "xxxxxxxxxxxxxxxxxxxx🇯🇵 yyyyyyyyyyyyyyyyyyyy", "🇵", 14, "...🇯🇵..."},
+	}
+	for _, tt := range tests {
+		i := strings.Index(tt.text, tt.pattern)
+		got, moved := tt.unit.truncate(tt.text, [][2]int{{i, i + len(tt.pattern)}}, tt.limit)
+		if got != tt.want {
+			t.Errorf("unit %d: truncate(%q, %d) = %q, want %q", tt.unit, tt.text, tt.limit, got, tt.want)
+			continue
+		}
+		if len(moved) != 1 || !strings.Contains(got[moved[0][0]:moved[0][1]], tt.pattern) {
+			t.Errorf("unit %d: truncate(%q, %d) moved the match to %v", tt.unit, tt.text, tt.limit, moved)
+		}
+	}
+}
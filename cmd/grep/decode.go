@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// decoding finds tokens of one encoding in a line and decodes them, so
+// that --decode can match the pattern against what they contain.
+type decoding struct {
+	name   string
+	token  *regexp.Regexp
+	decode func(string) (string, bool)
+}
+
+// decodings are the encodings --decode accepts. The token patterns ask
+// for a minimum length so that ordinary words are not taken for base64
+// or hex.
+var decodings = []*decoding{
+	{"base64", regexp.MustCompile(`[A-Za-z0-9+/_-]{8,}={0,2}`), decodeBase64},
+	{"url", regexp.MustCompile(`[^\s"'<>]*%[0-9A-Fa-f]{2}[^\s"'<>]*`), decodeURL},
+	{"hex", regexp.MustCompile(`\b(?:[0-9A-Fa-f]{2}){8,}\b`), decodeHex},
+}
+
+// parseDecodings parses the comma-separated list given to --decode.
+func parseDecodings(list string) ([]*decoding, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var ds []*decoding
+	for _, name := range strings.Split(list, ",") {
+		i := -1
+		for j, d := range decodings {
+			if d.name == name {
+				i = j
+			}
+		}
+		if i < 0 {
+			return nil, msgInvalidDecode.errorf(name)
+		}
+		ds = append(ds, decodings[i])
+	}
+	return ds, nil
+}
+
+func decodeBase64(s string) (string, bool) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+func decodeURL(s string) (string, bool) {
+	d, err := url.QueryUnescape(s)
+	return d, err == nil
+}
+
+func decodeHex(s string) (string, bool) {
+	b, err := hex.DecodeString(s)
+	return string(b), err == nil
+}
+
+// decodedMatch is an encoded token whose decoded text matches.
+type decodedMatch struct {
+	encoding string
+	token    [2]int // byte offsets of the token in the line
+	snippet  string // the decoded text around the first match
+}
+
+// snippetContext is how many bytes of decoded text are kept on each side
+// of the match in a snippet.
+const snippetContext = 20
+
+// findDecoded returns the tokens in line that decode, with m's decodings,
+// to text the pattern matches. A token found by more than one decoding is
+// reported for the first that matches.
+func (m *matcher) findDecoded(line string) []decodedMatch {
+	var found []decodedMatch
+	seen := make(map[[2]int]bool)
+	for _, d := range m.decodings {
+		for _, loc := range d.token.FindAllStringIndex(line, -1) {
+			token := [2]int{loc[0], loc[1]}
+			if seen[token] {
+				continue
+			}
+			decoded, ok := d.decode(line[loc[0]:loc[1]])
+			if !ok {
+				continue
+			}
+			decoded, escapes := m.mode.escape(decoded)
+			matches := m.findAll(decoded, escapes)
+			if len(matches) == 0 {
+				continue
+			}
+			seen[token] = true
+			found = append(found, decodedMatch{encoding: d.name, token: token, snippet: snippet(decoded, matches[0])})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].token[0] < found[j].token[0] })
+	return found
+}
+
+// snippet returns the text around match in s, marking cut ends with "...".
+func snippet(s string, match [2]int) string {
+	start, end := max(0, match[0]-snippetContext), min(len(s), match[1]+snippetContext)
+	for start > 0 && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	for end < len(s) && !utf8.RuneStart(s[end]) {
+		end++
+	}
+	text := s[start:end]
+	if start > 0 {
+		text = "..." + text
+	}
+	if end < len(s) {
+		text += "..."
+	}
+	return text
+}
+
+// withTokens adds the decoded tokens to matches, merging any that overlap.
+func withTokens(matches [][2]int, decoded []decodedMatch) [][2]int {
+	all := append([][2]int(nil), matches...)
+	for _, d := range decoded {
+		all = append(all, d.token)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i][0] < all[j][0] })
+	var merged [][2]int
+	for _, mt := range all {
+		if k := len(merged); k > 0 && mt[0] < merged[k-1][1] {
+			merged[k-1][1] = max(merged[k-1][1], mt[1])
+			continue
+		}
+		merged = append(merged, mt)
+	}
+	return merged
+}
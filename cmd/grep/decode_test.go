@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseDecodings(t *testing.T) {
+	ds, err := parseDecodings("hex,base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds) != 2 || ds[0].name != "hex" || ds[1].name != "base64" {
+		t.Errorf("got %v", ds)
+	}
+	if ds, err := parseDecodings(""); ds != nil || err != nil {
+		t.Errorf("empty list: got %v, %v", ds, err)
+	}
+	if _, err := parseDecodings("base64,rot13"); err == nil {
+		t.Error("rot13 was accepted")
+	}
+}
+
+func TestDecoders(t *testing.T) {
+	tests := []struct {
+		decode  func(string) (string, bool)
+		in, out string
+		ok      bool
+	}{
+		{decodeBase64, "c2VjcmV0IHRva2Vu", "secret token", true},
+		{decodeBase64, "c2VjcmV0", "secret", true},
+		{decodeBase64, "c2VjcmV0IQ", "secret!", true}, // unpadded
+		{decodeBase64, "Pz8_Pz8-", "?????>", true},    // URL alphabet
+		{decodeBase64, "not*base64", "", false},
+		{decodeURL, "a%20b%2Fc", "a b/c", true},
+		{decodeURL, "a+b", "a b", true},
+		{decodeURL, "bad%zz", "", false},
+		{decodeHex, "6e6565646c65", "needle", true},
+		{decodeHex, "6E6565646C65", "needle", true},
+		{decodeHex, "6e656", "", false},
+	}
+	for i, tt := range tests {
+		out, ok := tt.decode(tt.in)
+		if ok != tt.ok || ok && out != tt.out {
+			t.Errorf("%d: decode(%q) = %q, %v, want %q, %v", i, tt.in, out, ok, tt.out, tt.ok)
+		}
+	}
+}
+
+func TestFindDecoded(t *testing.T) {
+	tests := []struct {
+		decode, line string
+		want         []string // encoding@start-end: snippet
+	}{
+		{"base64", "auth c2VjcmV0IG5lZWRsZSBoZXJl ok", []string{"base64@5-29: secret needle here"}},
+		{"url", "GET /q?s=a%20needle%21 HTTP", []string{"url@4-22: /q?s=a needle!"}},
+		{"hex", "data 6e6565646c6520696e2068657821", []string{"hex@5-33: needle in hex!"}},
+		{"hex", "deadbeef", nil}, // too short to be taken for hex
+		{"base64,hex", "6e6565646c6520696e2068657821", []string{"hex@0-28: needle in hex!"}},
+		{"base64", "bmVlZGxl plain needle", []string{"base64@0-8: needle"}},
+		{"base64", "nothing encoded here", nil},
+	}
+	for _, tt := range tests {
+		ds, err := parseDecodings(tt.decode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := newMatcher("needle", false, modeUTF8)
+		m.decodings = ds
+		var got []string
+		for _, d := range m.findDecoded(tt.line) {
+			got = append(got, fmt.Sprintf("%s@%d-%d: %s", d.encoding, d.token[0], d.token[1], d.snippet))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("--decode %s in %q: got %q, want %q", tt.decode, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("a", 30) + "needle" + strings.Repeat("b", 30)
+	i := strings.Index(long, "needle")
+	want := "..." + strings.Repeat("a", 20) + "needle" + strings.Repeat("b", 20) + "..."
+	if got := snippet(long, [2]int{i, i + 6}); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := snippet("short needle", [2]int{6, 12}); got != "short needle" {
+		t.Errorf("got %q", got)
+	}
+	// The cut never splits a character.
+	wide := strings.Repeat("日", 10) + "needle"
+	if got := snippet(wide, [2]int{30, 36}); got != "...日日日日日日日needle" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWithTokens(t *testing.T) {
+	got := withTokens([][2]int{{0, 2}, {10, 12}}, []decodedMatch{{token: [2]int{1, 5}}, {token: [2]int{20, 30}}})
+	if want := [][2]int{{0, 5}, {10, 12}, {20, 30}}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
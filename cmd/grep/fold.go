@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// widthFold is the --width-fold transform. Full-width ASCII and the
+// ideographic space become ASCII, and half-width katakana become
+// full-width, with a following half-width sound mark joined as in
+// "ｶﾞ" → "ガ".
+type widthFold struct{}
+
+func (widthFold) apply(s string) (string, [][2]int) {
+	normTables.once.Do(loadNormTables)
+	return foldRunes(s, func(r, next rune) (rune, bool) {
+		w, ok := normTables.width[r]
+		if !ok {
+			return r, false
+		}
+		// Sound marks fold to combining marks, which compose with the kana.
+		if m, ok := normTables.width[next]; ok && (m == 0x3099 || m == 0x309A) {
+			if c, ok := normTables.pairs[[2]rune{w, m}]; ok {
+				return c, true
+			}
+		}
+		return w, false
+	})
+}
+
+// kanaFold is the --kana-fold transform, which turns hiragana into
+// katakana.
+type kanaFold struct{}
+
+func (kanaFold) apply(s string) (string, [][2]int) {
+	return foldRunes(s, func(r, next rune) (rune, bool) {
+		switch {
+		case r >= 'ぁ' && r <= 'ゖ', r == 'ゝ', r == 'ゞ':
+			return r + 'ァ' - 'ぁ', false
+		}
+		return r, false
+	})
+}
+
+// foldRunes replaces each rune of s by fold, which also reports whether
+// it took in the rune after it. It returns spans as textTransform.apply
+// does. Invalid UTF-8 is kept as it is.
+func foldRunes(s string, fold func(r, next rune) (rune, bool)) (string, [][2]int) {
+	if isASCII(s) {
+		return s, nil
+	}
+	var b strings.Builder
+	var spans [][2]int
+	changed := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < utf8.RuneSelf || r == utf8.RuneError && size == 1 {
+			b.WriteByte(s[i])
+			spans = append(spans, [2]int{i, i + 1})
+			i++
+			continue
+		}
+		next, nextSize := utf8.DecodeRuneInString(s[i+size:])
+		f, joined := fold(r, next)
+		end := i + size
+		if joined {
+			end += nextSize
+		}
+		changed = changed || f != r || joined
+		n := b.Len()
+		b.WriteRune(f)
+		for ; n < b.Len(); n++ {
+			spans = append(spans, [2]int{i, end})
+		}
+		i = end
+	}
+	if !changed {
+		return s, nil
+	}
+	return b.String(), spans
+}
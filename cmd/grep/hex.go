@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// hexElem is one element of a --hex pattern: a byte, some of whose bits
+// may be wildcards, or a jump over min to max bytes of anything.
+type hexElem struct {
+	value, mask byte // the byte matches b if b&mask == value
+	jump        bool
+	min, max    int
+}
+
+// hexPattern is a byte signature in the syntax of YARA's hex strings:
+// "DE AD ?? EF" with "??" for any byte, "D?" or "?E" for any nibble and
+// "[2-4]" or "[3]" to skip a number of bytes. Spaces between bytes are
+// optional.
+type hexPattern []hexElem
+
+// maxHexJump is the most bytes one jump may skip, which bounds the window
+// a search holds and the distance searched after each jump.
+const maxHexJump = 4096
+
+func parseHexPattern(s string) (hexPattern, error) {
+	var p hexPattern
+	rest := s
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, msgInvalidHex.errorf(s, rest)
+			}
+			lo, hi, isRange := strings.Cut(rest[1:end], "-")
+			if !isRange {
+				hi = lo
+			}
+			min, err1 := strconv.Atoi(lo)
+			max, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil || min < 0 || max < min || len(p) == 0 || p[len(p)-1].jump {
+				return nil, msgInvalidHex.errorf(s, rest)
+			}
+			if max > maxHexJump {
+				return nil, msgHexJumpTooWide.errorf(s, maxHexJump)
+			}
+			p = append(p, hexElem{jump: true, min: min, max: max})
+			rest = rest[end+1:]
+			continue
+		}
+		if len(rest) < 2 {
+			return nil, msgInvalidHex.errorf(s, rest)
+		}
+		var e hexElem
+		for i, shift := range []uint{4, 0} {
+			if rest[i] == '?' {
+				continue
+			}
+			n, err := strconv.ParseUint(rest[i:i+1], 16, 8)
+			if err != nil {
+				return nil, msgInvalidHex.errorf(s, rest)
+			}
+			e.value |= byte(n) << shift
+			e.mask |= 0xf << shift
+		}
+		p = append(p, e)
+		rest = rest[2:]
+	}
+	if len(p) == 0 || p[len(p)-1].jump {
+		return nil, msgInvalidHex.errorf(s, rest)
+	}
+	return p, nil
+}
+
+// maxLen returns the most bytes a match can span.
+func (p hexPattern) maxLen() int {
+	n := 0
+	for _, e := range p {
+		if e.jump {
+			n += e.max
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// hexRun is a stretch of pattern bytes without jumps, with the jump that
+// comes before it.
+type hexRun struct {
+	elems    hexPattern
+	min, max int    // bytes the jump before the run may skip
+	lit      []byte // the longest stretch of elems without wildcards
+	off      int    // index of lit in elems
+}
+
+// runs splits p at its jumps.
+func (p hexPattern) runs() []hexRun {
+	var runs []hexRun
+	for k := 0; k < len(p); {
+		var r hexRun
+		if p[k].jump {
+			r.min, r.max = p[k].min, p[k].max
+			k++
+		}
+		end := k
+		for end < len(p) && !p[end].jump {
+			end++
+		}
+		r.elems = p[k:end]
+		for i := 0; i < len(r.elems); {
+			j := i
+			for j < len(r.elems) && r.elems[j].mask == 0xff {
+				j++
+			}
+			if j-i > len(r.lit) {
+				r.lit, r.off = make([]byte, 0, j-i), i
+				for _, e := range r.elems[i:j] {
+					r.lit = append(r.lit, e.value)
+				}
+			}
+			i = j + 1
+		}
+		runs = append(runs, r)
+		k = end
+	}
+	return runs
+}
+
+// at reports whether the run matches at b[i:].
+func (r *hexRun) at(b []byte, i int) bool {
+	if i+len(r.elems) > len(b) {
+		return false
+	}
+	for j, e := range r.elems {
+		if b[i+j]&e.mask != e.value {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the first i in [lo, hi] at which the run matches b, or -1.
+// A run with literal bytes is found with bytes.Index rather than tried at
+// each i.
+func (r *hexRun) find(b []byte, lo, hi int) int {
+	hi = min(hi, len(b)-len(r.elems))
+	for lo <= hi {
+		if len(r.lit) > 0 {
+			j := bytes.Index(b[lo+r.off:hi+r.off+len(r.lit)], r.lit)
+			if j < 0 {
+				return -1
+			}
+			lo += j
+		}
+		if r.at(b, lo) {
+			return lo
+		}
+		lo++
+	}
+	return -1
+}
+
+// hexMatcher matches the runs of a pattern in one buffer. For each run
+// after the first it records, from the end of the buffer back, where the
+// rest of the pattern matches, so that crossing a jump is one lookup rather
+// than a try of every length, and the work is linear in the buffer and the
+// pattern however many jumps there are.
+type hexMatcher struct {
+	runs []hexRun
+	b    []byte
+	ends [][]int // ends[r][i]: end of the match of runs[r:] at b[i:], or -1
+	next [][]int // next[r][i]: the first j >= i with ends[r][j] >= 0, or len(b)
+}
+
+func newHexMatcher(runs []hexRun, b []byte) *hexMatcher {
+	m := &hexMatcher{runs: runs, b: b, ends: make([][]int, len(runs)), next: make([][]int, len(runs))}
+	for r := len(runs) - 1; r > 0; r-- {
+		ends := make([]int, len(b))
+		for i := range ends {
+			ends[i] = -1
+		}
+		for i := runs[r].find(b, 0, len(b)); i >= 0; i = runs[r].find(b, i+1, len(b)) {
+			ends[i] = m.rest(r, i)
+		}
+		next := make([]int, len(b)+1)
+		next[len(b)] = len(b)
+		for i := len(b) - 1; i >= 0; i-- {
+			next[i] = next[i+1]
+			if ends[i] >= 0 {
+				next[i] = i
+			}
+		}
+		m.ends[r], m.next[r] = ends, next
+	}
+	return m
+}
+
+// rest returns the end of the match of runs[r:] at b[i:], given that
+// runs[r] matches there, or -1. Jumps are crossed to the nearest place the
+// rest matches.
+func (m *hexMatcher) rest(r, i int) int {
+	i += len(m.runs[r].elems)
+	if r+1 == len(m.runs) {
+		return i
+	}
+	next := m.runs[r+1]
+	lo := i + next.min
+	if lo >= len(m.b) {
+		return -1
+	}
+	j := m.next[r+1][lo]
+	if j == len(m.b) || j > i+next.max {
+		return -1
+	}
+	return m.ends[r+1][j]
+}
+
+// match returns the end of the match at b[i:], or -1.
+func (m *hexMatcher) match(i int) int {
+	if !m.runs[0].at(m.b, i) {
+		return -1
+	}
+	return m.rest(0, i)
+}
+
+// hexSearch is a --hex search: the pattern, and the printer for its hits
+// in place of the line printers.
+type hexSearch struct {
+	pattern hexPattern
+	out     *hexPrinter
+}
+
+// search streams r through a window that holds a possible match and its
+// context, so that files of any size take constant memory.
+func (h *hexSearch) search(path string, r io.Reader) error {
+	before, after := h.out.before, h.out.after
+	reach := h.pattern.maxLen() + after // bytes needed past a match start
+	chunk := make([]byte, 64*1024)
+	var buf []byte
+	var base int64 // offset in r of buf[0]
+	pos := 0       // index in buf of the next start to try
+	runs := h.pattern.runs()
+	for {
+		n, err := io.ReadFull(r, chunk)
+		buf = append(buf, chunk[:n]...)
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			return err
+		}
+		limit := len(buf) - reach
+		if eof {
+			limit = len(buf)
+		}
+		m := newHexMatcher(runs, buf)
+		for ; pos < limit; pos++ {
+			if pos = runs[0].find(buf, pos, limit-1); pos < 0 {
+				pos = limit
+				break
+			}
+			end := m.match(pos)
+			if end < 0 {
+				continue
+			}
+			done, err := h.out.printHit(path, buf, base, pos, end)
+			if err != nil || done {
+				return err
+			}
+			pos = max(pos, end-1)
+		}
+		if eof {
+			return nil
+		}
+		// Keep only what the before-context of the next hit needs.
+		if drop := pos - before; drop > 0 {
+			buf = append(buf[:0], buf[drop:]...)
+			base += int64(drop)
+			pos -= drop
+		}
+	}
+}
+
+// hexRow is the number of bytes in a row of the hexdump.
+const hexRow = 16
+
+// hexPrinter writes each hit of a --hex search as its offset followed by a
+// hexdump in the style of "hexdump -C", showing before and after bytes of
+// context around it.
+type hexPrinter struct {
+	printerConfig
+	w             *bufio.Writer
+	before, after int
+	filesOnly     bool // -l: write only the path, once
+}
+
+func newHexPrinter(w io.Writer, cfg printerConfig, before, after int, filesOnly bool) *hexPrinter {
+	return &hexPrinter{printerConfig: cfg, w: bufio.NewWriter(w), before: before, after: after, filesOnly: filesOnly}
+}
+
+// printHit writes the match buf[start:end], where buf begins at offset base
+// of the file. done is true once nothing more is wanted from the file.
+func (p *hexPrinter) printHit(path string, buf []byte, base int64, start, end int) (done bool, err error) {
+	if p.filesOnly {
+		p.writePath(p.w, path, "", 0, 0)
+		return true, p.w.WriteByte(p.pathTerminator('\n'))
+	}
+	if p.withPath {
+		p.writePath(p.w, path, "", 0, 0)
+		p.w.WriteByte(p.pathTerminator(':'))
+	}
+	offset := "0x" + strconv.FormatInt(base+int64(start), 16)
+	if p.color {
+		offset = colorLineNum + offset + colorReset
+	}
+	p.w.WriteString(offset)
+	p.w.WriteByte('\n')
+
+	lo, hi := max(0, start-p.before), min(len(buf), end+p.after)
+	hidden := p.hidden(buf[lo:hi])
+	first := (base + int64(lo)) / hexRow * hexRow
+	for row := first; row < base+int64(hi); row += hexRow {
+		if row > first {
+			p.w.WriteByte('\n')
+		}
+		p.writeRow(buf, base, row, lo, hi, start, end, hidden)
+	}
+	return false, p.w.WriteByte('\n')
+}
+
+// hidden returns which bytes of b --redact masks, found in the dump's
+// ASCII column as a whole so that text spanning rows is masked too.
+func (p *hexPrinter) hidden(b []byte) []bool {
+	if p.redact == nil {
+		return nil
+	}
+	ascii := make([]byte, len(b))
+	for i, c := range b {
+		ascii[i] = printable(c)
+	}
+	var hidden []bool
+	for _, s := range p.redact.spans(string(ascii)) {
+		if hidden == nil {
+			hidden = make([]bool, len(b))
+		}
+		for i := s[0]; i < s[1]; i++ {
+			hidden[i] = true
+		}
+	}
+	return hidden
+}
+
+// printable returns c as shown in the ASCII column of the hexdump.
+func printable(c byte) byte {
+	if c >= 0x20 && c < 0x7f {
+		return c
+	}
+	return '.'
+}
+
+// writeRow writes the row of the hexdump at offset row, leaving out bytes
+// outside buf[lo:hi], highlighting those in buf[start:end] and masking
+// those hidden by --redact, which is indexed from lo.
+func (p *hexPrinter) writeRow(buf []byte, base, row int64, lo, hi, start, end int, hidden []bool) {
+	fmt.Fprintf(p.w, "%08x  ", row)
+	var ascii strings.Builder
+	for k := int64(0); k < hexRow; k++ {
+		i := int(row + k - base)
+		if k == hexRow/2 {
+			p.w.WriteByte(' ')
+		}
+		if i < lo || i >= hi {
+			p.w.WriteString("   ")
+			ascii.WriteByte(' ')
+			continue
+		}
+		hex, char := fmt.Sprintf("%02x", buf[i]), string(printable(buf[i]))
+		if hidden != nil && hidden[i-lo] {
+			hex, char = "**", "*"
+		}
+		if p.color && i >= start && i < end {
+			hex, char = colorMatch+hex+colorReset, colorMatch+char+colorReset
+		}
+		p.w.WriteString(hex)
+		p.w.WriteByte(' ')
+		ascii.WriteString(char)
+	}
+	p.w.WriteString(" |" + ascii.String() + "|")
+}
+
+// The line printer methods are unused: hits go through printHit.
+
+func (p *hexPrinter) printMatch(lm *lineMatch) error                { return nil }
+func (p *hexPrinter) printContext(path string, c contextLine) error { return nil }
+func (p *hexPrinter) startFile()                                    {}
+
+func (p *hexPrinter) flush() error {
+	return p.w.Flush()
+}
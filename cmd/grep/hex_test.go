@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHexPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    hexPattern // nil if the pattern is invalid
+	}{
+		{"DE AD", hexPattern{{value: 0xde, mask: 0xff}, {value: 0xad, mask: 0xff}}},
+		{"dead", hexPattern{{value: 0xde, mask: 0xff}, {value: 0xad, mask: 0xff}}},
+		{"?? D? ?E", hexPattern{{}, {value: 0xd0, mask: 0xf0}, {value: 0x0e, mask: 0x0f}}},
+		{"41 [2-4] 42", hexPattern{{value: 0x41, mask: 0xff}, {jump: true, min: 2, max: 4}, {value: 0x42, mask: 0xff}}},
+		{"41 [3] 42", hexPattern{{value: 0x41, mask: 0xff}, {jump: true, min: 3, max: 3}, {value: 0x42, mask: 0xff}}},
+		{"41 [4096] 42", hexPattern{{value: 0x41, mask: 0xff}, {jump: true, min: 4096, max: 4096}, {value: 0x42, mask: 0xff}}},
+
+		{"", nil},
+		{"4", nil},
+		{"4G", nil},
+		{"41 4", nil},
+		{"[2] 41", nil},
+		{"41 [2]", nil},
+		{"41 [2", nil},
+		{"41 [x] 42", nil},
+		{"41 [4-2] 42", nil},
+		{"41 [-1] 42", nil},
+		{"41 [1] [2] 42", nil},
+		{"41 [0-4097] 42", nil},
+	}
+	for _, tt := range tests {
+		got, err := parseHexPattern(tt.pattern)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseHexPattern(%q) = %v, want an error", tt.pattern, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseHexPattern(%q): %v", tt.pattern, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseHexPattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseHexPattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHexMatch(t *testing.T) {
+	tests := []struct {
+		pattern, data string
+		start, end    int // -1, -1 if there is no match
+	}{
+		{"41 42", "xxAB", 2, 4},
+		{"41 ?? 43", "A?CABC", 0, 3},
+		{"4? 3F", "xA?", 1, 3},
+		{"?1 42", "qAB", 1, 3},
+		{"41 [2] 44", "ABCD", 0, 4},
+		{"41 [2] 44", "ABD", -1, -1},
+		{"41 [0-2] 44", "AD", 0, 2},
+		{"41 [1-5] 44 45", "ABCDE", 0, 5},
+		{"41 [1-5] 44 45", "ADE", -1, -1},
+		// The nearest place after a jump is taken.
+		{"41 [0-9] 42", "AxBxB", 0, 3},
+		// A later start is tried when an earlier one cannot finish.
+		{"41 [0-1] 42", "AxxAxB", 3, 6},
+		{"41 [0-3] 42 [0-3] 43", "AB..BC", 0, 6},
+		{"00 [0-8] 00 [0-8] 01", "\x00\x00\x00\x01", 0, 4},
+	}
+	for _, tt := range tests {
+		p, err := parseHexPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("parseHexPattern(%q): %v", tt.pattern, err)
+		}
+		b := []byte(tt.data)
+		runs := p.runs()
+		m := newHexMatcher(runs, b)
+		start, end := -1, -1
+		for i := runs[0].find(b, 0, len(b)); i >= 0; i = runs[0].find(b, i+1, len(b)) {
+			if e := m.match(i); e >= 0 {
+				start, end = i, e
+				break
+			}
+		}
+		if start != tt.start || end != tt.end {
+			t.Errorf("%q in %q: got [%d, %d), want [%d, %d)", tt.pattern, tt.data, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+// hexHits runs a --hex search of data and returns the hexdump.
+func hexHits(t *testing.T, pattern string, data []byte) string {
+	t.Helper()
+	p, err := parseHexPattern(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	h := &hexSearch{pattern: p, out: newHexPrinter(&out, printerConfig{}, 0, 0, false)}
+	if err := h.search("data", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	h.out.flush()
+	return out.String()
+}
+
+func TestHexSearchAcrossChunks(t *testing.T) {
+	data := make([]byte, 200*1024)
+	copy(data[150*1024:], "DEAD..BEEF")
+	got := hexHits(t, "44 45 41 44 [1-4] 42 45 45 46", data)
+	if !strings.HasPrefix(got, "0x25800\n") {
+		t.Errorf("got %q, want a hit at 0x25800", got)
+	}
+}
+
+// Matching must not try each combination of jump lengths, which takes time
+// exponential in the number of jumps.
+func TestHexJumpsAreLinear(t *testing.T) {
+	data := make([]byte, 200*1024)
+	start := time.Now()
+	if got := hexHits(t, "00 [0-4096] 00 [0-4096] 00 [0-4096] 01", data); got != "" {
+		t.Errorf("got %q, want no hits", got)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("search took %v", d)
+	}
+}
+
+func TestHexSearchEmpty(t *testing.T) {
+	if got := hexHits(t, "41", nil); got != "" {
+		t.Errorf("got %q", got)
+	}
+}
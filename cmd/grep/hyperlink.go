@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hyperlinkPresets maps --hyperlink-format names to URL templates.
+var hyperlinkPresets = map[string]string{
+	"default":         "file://{host}{path}",
+	"file":            "file://{host}{path}",
+	"vscode":          "vscode://file{path}:{line}:{column}",
+	"vscode-insiders": "vscode-insiders://file{path}:{line}:{column}",
+	"cursor":          "cursor://file{path}:{line}:{column}",
+	"kitty":           "file://{host}{path}#{line}",
+	"macvim":          "mvim://open?url=file://{path}&line={line}&column={column}",
+	"textmate":        "txmt://open?url=file://{path}&line={line}&column={column}",
+}
+
+// hyperlinkFormat turns a file location into a URL for OSC 8 links.
+type hyperlinkFormat struct {
+	template string
+	host     string
+	root     *sandbox // where relative paths are opened from, if set
+}
+
+// parseHyperlinkFormat accepts a preset name or a template using {path},
+// {line}, {column} and {host}. It returns nil for "none".
+func parseHyperlinkFormat(format string) (*hyperlinkFormat, error) {
+	if format == "none" {
+		return nil, nil
+	}
+	if preset, ok := hyperlinkPresets[format]; ok {
+		format = preset
+	}
+	if !strings.Contains(format, "{path}") {
+		return nil, msgHyperlinkNoPath.errorf(format)
+	}
+
+	rest := format
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(rest[i:], '}')
+		if j < 0 {
+			return nil, msgHyperlinkUnclosed.errorf(format)
+		}
+		switch name := rest[i+1 : i+j]; name {
+		case "path", "line", "column", "host":
+		default:
+			return nil, msgHyperlinkUnknown.errorf(format, name)
+		}
+		rest = rest[i+j+1:]
+	}
+
+	host, _ := os.Hostname()
+	return &hyperlinkFormat{template: format, host: host}, nil
+}
+
+// url expands the template for path at the 1-based line and column.
+func (h *hyperlinkFormat) url(path string, line, column int) string {
+	abs, err := filepath.Abs(h.root.resolve(path))
+	if err != nil {
+		abs = path
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs // Windows drive letters
+	}
+	return strings.NewReplacer(
+		"{path}", (&url.URL{Path: abs}).EscapedPath(),
+		"{line}", strconv.Itoa(line),
+		"{column}", strconv.Itoa(column),
+		"{host}", h.host,
+	).Replace(h.template)
+}
+
+// wrap returns text as an OSC 8 hyperlink to path.
+func (h *hyperlinkFormat) wrap(text, path string, line, column int) string {
+	return "\x1b]8;;" + h.url(path, line, column) + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// defaultRegexSizeLimit is the default --regex-size-limit. Go's parser
+// already caps repeat counts at 1000, so only large alternations and
+// nested classes get near it.
+const defaultRegexSizeLimit = 10000
+
+// limitError reports which resource limit stopped a search.
+type limitError struct {
+	flag  string // the option that sets the limit
+	value string // the limit as given
+}
+
+func (e *limitError) Error() string {
+	return msgLimitExceeded.format(e.flag, e.value)
+}
+
+// compileLimited compiles expr, refusing programs of more than limit
+// instructions so that a hostile pattern cannot exhaust memory.
+func compileLimited(expr string, limit int) (*regexp.Regexp, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > limit {
+		return nil, &limitError{flag: "regex-size-limit", value: msgLimitInstructions.format(limit, len(prog.Inst))}
+	}
+	return regexp.Compile(expr)
+}
+
+// defaultDocumentLimit is how many bytes a document or notebook, which is
+// read whole, may take in memory without --max-memory.
+const defaultDocumentLimit = 1 << 30
+
+// documentLimit returns how many bytes a document or notebook may take in
+// memory, and the error that reports going over it.
+func (o *searchOptions) documentLimit() (int64, *limitError) {
+	if o.maxMemory > 0 {
+		return o.maxMemory, &limitError{flag: "max-memory", value: o.maxMemoryArg}
+	}
+	return defaultDocumentLimit, &limitError{flag: "max-memory", value: "1G"}
+}
+
+// parseSize parses a positive byte count with an optional K, M or G
+// suffix, which may be followed by B.
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	n := strings.TrimSuffix(strings.ToUpper(s), "B")
+	switch {
+	case strings.HasSuffix(n, "K"):
+		mult, n = 1<<10, n[:len(n)-1]
+	case strings.HasSuffix(n, "M"):
+		mult, n = 1<<20, n[:len(n)-1]
+	case strings.HasSuffix(n, "G"):
+		mult, n = 1<<30, n[:len(n)-1]
+	}
+	v, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || v <= 0 || v > math.MaxInt64/mult {
+		return 0, msgInvalidSize.errorf(s)
+	}
+	return v * mult, nil
+}
@@ -1,32 +1,327 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strings"
 )
 
+func usage() {
+	fmt.Println(msgUsage)
+	fmt.Println()
+	fmt.Println(msgOptions)
+	printDefaults(flag.CommandLine)
+}
+
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Println("Usage: grep <pattern> <file>")
+	// Use "mygrep -- secrets" to search for the word itself.
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		os.Exit(runSecrets(os.Args[2:]))
+	}
+
+	ignoreCase := flag.Bool("i", false, msgFlagIgnoreCase.String())
+	tui := flag.Bool("tui", false, msgFlagTUI.String())
+	hyperlinkFormat := flag.String("hyperlink-format", "none", msgFlagHyperlinkFormat.String())
+	pager := flag.Bool("pager", false, msgFlagPager.String())
+	lineNumbers := flag.Bool("n", false, msgFlagLineNumbers.String())
+	color := flag.String("color", "never", msgFlagColor.String())
+	heading := flag.Bool("heading", false, msgFlagHeading.String())
+	noHeading := flag.Bool("no-heading", false, msgFlagNoHeading.String())
+	var pretty bool
+	flag.BoolVar(&pretty, "p", false, msgFlagAlias.format("pretty"))
+	flag.BoolVar(&pretty, "pretty", false, msgFlagPretty.String())
+	after := flag.Int("A", 0, msgFlagAfter.String())
+	before := flag.Int("B", 0, msgFlagBefore.String())
+	context := flag.Int("C", 0, msgFlagContext.String())
+	format := flag.String("format", "", msgFlagFormat.String())
+	formatPer := flag.String("format-per", "line", msgFlagFormatPer.String())
+	var filesWithMatches, null bool
+	flag.BoolVar(&filesWithMatches, "l", false, msgFlagAlias.format("files-with-matches"))
+	flag.BoolVar(&filesWithMatches, "files-with-matches", false, msgFlagFilesWithMatches.String())
+	flag.BoolVar(&null, "Z", false, msgFlagAlias.format("null"))
+	flag.BoolVar(&null, "null", false, msgFlagNull.String())
+	absolutePath := flag.Bool("absolute-path", false, msgFlagAbsolutePath.String())
+	stripPrefix := flag.String("strip-prefix", "", msgFlagStripPrefix.String())
+	pathSeparator := flag.String("path-separator", "", msgFlagPathSeparator.String())
+	baseline := flag.String("baseline", "", msgFlagBaseline.String())
+	baselineFixed := flag.Bool("baseline-fixed", false, msgFlagBaselineFixed.String())
+	saveBaselinePath := flag.String("save-baseline", "", msgFlagSaveBaseline.String())
+	var redacts redactFlag
+	flag.Var(&redacts, "redact", msgFlagRedact.String())
+	regexSizeLimit := flag.Int("regex-size-limit", defaultRegexSizeLimit, msgFlagRegexSizeLimit.String())
+	maxMemory := flag.String("max-memory", "", msgFlagMaxMemory.String())
+	root := flag.String("root", "", msgFlagRoot.String())
+	normalize := flag.String("normalize", "", msgFlagNormalize.String())
+	foldWidth := flag.Bool("width-fold", false, msgFlagWidthFold.String())
+	foldKana := flag.Bool("kana-fold", false, msgFlagKanaFold.String())
+	showColumn := flag.Bool("column", false, msgFlagColumn.String())
+	columnUnitName := flag.String("column-unit", "byte", msgFlagColumnUnit.String())
+	maxColumns := flag.Int("max-columns", 0, msgFlagMaxColumns.String())
+	notebookOutputs := flag.Bool("notebook-outputs", false, msgFlagNotebookOutputs.String())
+	decodeList := flag.String("decode", "", msgFlagDecode.String())
+	hexMode := flag.Bool("hex", false, msgFlagHex.String())
+	searchRunsOnly := flag.Bool("strings", false, msgFlagStrings.String())
+	stringsMin := flag.Int("strings-min", 4, msgFlagStringsMin.String())
+	textModeName := flag.String("text-mode", "utf8", msgFlagTextMode.String())
+	passthru := flag.Bool("passthru", false, msgFlagPassthru.String())
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+
+	mode, err := parseTextMode(*textModeName)
+	if err != nil {
+		fmt.Println(msgError, err)
 		os.Exit(1)
 	}
+	var sb *sandbox
+	if *root != "" {
+		sb, err = newSandbox(*root)
+		if err != nil {
+			fmt.Println(msgError, err)
+			os.Exit(1)
+		}
+	}
 
-	// pattern := os.Args[1]
-	file := os.Args[2]
+	if *tui {
+		if len(args) == 0 {
+			usage()
+			os.Exit(1)
+		}
+		if err := runTUI(args, *ignoreCase, mode, sb); err != nil {
+			fmt.Println(msgError, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	content, err := os.ReadFile(file)
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	norm, err := parseNormForm(*normalize)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+	var transforms []textTransform
+	if norm != nil {
+		transforms = append(transforms, norm)
+	}
+	if *foldWidth {
+		transforms = append(transforms, widthFold{})
+	}
+	if *foldKana {
+		transforms = append(transforms, kanaFold{})
+	}
+	if mode == modeBytes && len(transforms) > 0 {
+		// The transforms work on characters, which bytes mode has none of.
+		fmt.Println(msgError, msgTextModeBytes)
 		os.Exit(1)
 	}
+	var hexPat hexPattern
+	if *hexMode {
+		if hexPat, err = parseHexPattern(args[0]); err != nil {
+			fmt.Println(msgError, err)
+			os.Exit(1)
+		}
+	}
+	m := newMatcher(args[0], *ignoreCase, mode, transforms...)
+	if m.decodings, err = parseDecodings(*decodeList); err != nil {
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+	files := args[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	// Matches in a document are located by the part they are in, which
+	// is shown with the path.
+	withPath := len(files) > 1 || *searchRunsOnly
+	for _, file := range files {
+		if isOfficeDocument(file) || isNotebook(file) {
+			withPath = true
+		}
+	}
 
-	contentString := string(content)
-	lines := strings.Split(contentString, "\n")
-	for _, line := range lines {
-		pattern := os.Args[1]
-		if strings.Contains(line, pattern) {
-			fmt.Println(line)
+	hl, err := parseHyperlinkFormat(*hyperlinkFormat)
+	if err != nil {
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+	if hl != nil {
+		hl.root = sb
+	}
+	paths, err := newPathFormat(*absolutePath, *stripPrefix, *pathSeparator, sb)
+	if err != nil {
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+	redact, err := newRedactor(redacts, *regexSizeLimit)
+	if err != nil {
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+	unit, err := parseColumnUnit(*columnUnitName)
+	if err != nil {
+		fmt.Println(msgError, err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	var pw *pagerWriter
+	tty := isTerminal(int(os.Stdout.Fd()))
+	if *pager && tty {
+		if rows, _, err := termSize(int(os.Stdout.Fd())); err == nil && rows > 0 {
+			pw = newPagerWriter(os.Stdout, pagerCommand(), rows)
+			out = pw
+		}
+	}
+
+	if *after == 0 {
+		*after = *context
+	}
+	if *before == 0 {
+		*before = *context
+	}
+
+	cfg := printerConfig{
+		withPath:    withPath,
+		lineNumbers: *lineNumbers || pretty,
+		contextual:  *after > 0 || *before > 0,
+		null:        null,
+		escapePaths: tty && !null,
+		paths:       paths,
+		redact:      redact,
+		column:      *showColumn,
+		columnUnit:  unit,
+		maxColumns:  *maxColumns,
+	}
+	switch *color {
+	case "always":
+		cfg.color = true
+	case "auto":
+		cfg.color = tty
+	case "never":
+	default:
+		fmt.Println(msgInvalidColor, *color)
+		os.Exit(1)
+	}
+	if pretty {
+		cfg.color = true
+	}
+	if tty {
+		cfg.hyperlink = hl
+	}
+
+	opts := &searchOptions{
+		before:          *before,
+		after:           *after,
+		passthru:        *passthru,
+		root:            sb,
+		notebookOutputs: *notebookOutputs,
+	}
+	if *searchRunsOnly {
+		if *stringsMin < 1 {
+			fmt.Println(msgInvalidStringsMin, *stringsMin)
+			os.Exit(1)
+		}
+		opts.stringsMin = *stringsMin
+	}
+	if *maxMemory != "" {
+		n, err := parseSize(*maxMemory)
+		if err != nil {
+			fmt.Println(msgInvalidMaxMemory, *maxMemory)
+			os.Exit(1)
 		}
+		opts.maxMemory, opts.maxMemoryArg = n, *maxMemory
+	}
+	if *passthru {
+		opts.before, opts.after = 0, 0
+		cfg.contextual = false
+	}
+	var p printer
+	switch {
+	case *hexMode:
+		hp := newHexPrinter(out, cfg, *before, *after, filesWithMatches)
+		opts.hex = &hexSearch{pattern: hexPat, out: hp}
+		p = hp
+	case filesWithMatches:
+		p = newPathPrinter(out, cfg)
+		opts.before, opts.after, opts.passthru = 0, 0, false
+		opts.firstOnly = true
+	case *format != "":
+		if *formatPer != "line" && *formatPer != "match" {
+			fmt.Println(msgInvalidFormatPer, *formatPer)
+			os.Exit(1)
+		}
+		p, err = newTemplatePrinter(out, *format, *formatPer == "match", paths, redact, unit)
+		if err != nil {
+			fmt.Println(msgError, err)
+			os.Exit(1)
+		}
+	case (*heading || pretty) && !*noHeading:
+		p = newHeadingPrinter(out, cfg)
+	default:
+		p = newStandardPrinter(out, cfg)
+	}
+
+	var bp *baselinePrinter
+	if *baseline != "" {
+		b, err := loadBaseline(*baseline, args[0])
+		if err != nil {
+			fmt.Println(msgError, err)
+			os.Exit(1)
+		}
+		bp = newBaselinePrinter(p, b, *passthru, redact, sb)
+		p = bp
+		// A file's first match may be a known one.
+		opts.firstOnly = false
+	}
+	var rec *recordingPrinter
+	if *saveBaselinePath != "" {
+		rec = &recordingPrinter{printer: p, redact: redact, root: sb}
+		p = rec
+		opts.firstOnly = false
+	}
+
+	status := 0
+	var searched []string
+	for _, file := range files {
+		p.startFile()
+		err := searchFile(p, m, file, opts)
+		if errors.Is(err, errPagerClosed) {
+			break
+		}
+		if err != nil {
+			p.flush()
+			fmt.Fprintln(out, msgErrorReadingFile, err)
+			status = 1
+			continue
+		}
+		if file == "-" {
+			file = stdinName
+		}
+		searched = append(searched, file)
+	}
+	p.flush()
+	if bp != nil && *baselineFixed {
+		bp.writeFixed(out, searched, cfg.escapePaths)
+	}
+	if rec != nil {
+		if err := saveBaseline(*saveBaselinePath, args[0], rec.entries); err != nil {
+			fmt.Fprintln(out, msgError, err)
+			status = 1
+		}
+	}
+	// New matches fail a --baseline run, so that CI can ratchet them down.
+	if bp != nil && bp.newCount > 0 {
+		status = 1
+	}
+	if pw != nil {
+		pw.Close()
 	}
+	os.Exit(status)
 }
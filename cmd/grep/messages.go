@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// message is a user-facing string in each supported language.
+type message struct {
+	en, ja string
+}
+
+// messages holds every message, so that they can be checked together.
+var messages []message
+
+// newMessage returns the message with the given English and Japanese text,
+// registering it in messages. The arguments are positional, so the
+// compiler rejects a message that is missing a translation.
+func newMessage(en, ja string) message {
+	m := message{en, ja}
+	messages = append(messages, m)
+	return m
+}
+
+// japanese selects the Japanese messages. Like gettext, the first of
+// LC_ALL, LC_MESSAGES and LANG that is set decides.
+var japanese = func() bool {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return strings.HasPrefix(v, "ja")
+		}
+	}
+	return false
+}()
+
+func (m message) String() string {
+	if japanese {
+		return m.ja
+	}
+	return m.en
+}
+
+// format formats the message as fmt.Sprintf does.
+func (m message) format(args ...any) string {
+	return fmt.Sprintf(m.String(), args...)
+}
+
+// errorf returns an error formatted from the message, as fmt.Errorf does.
+func (m message) errorf(args ...any) error {
+	return fmt.Errorf(m.String(), args...)
+}
+
+// printDefaults is flag.PrintDefaults with the default value note
+// translated.
+func printDefaults(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "  -%s", f.Name)
+		name, usage := flag.UnquoteUsage(f)
+		if name != "" {
+			b.WriteString(" " + name)
+		}
+		// Single-letter boolean flags fit on one line.
+		if b.Len() <= 4 {
+			b.WriteString("\t")
+		} else {
+			b.WriteString("\n    \t")
+		}
+		b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			def := f.DefValue
+			if fmt.Sprintf("%T", f.Value) == "*flag.stringValue" {
+				def = fmt.Sprintf("%q", def)
+			}
+			b.WriteString(" " + msgDefault.format(def))
+		}
+		fmt.Fprintln(fs.Output(), b.String())
+	})
+}
+
+// General messages.
+var (
+	msgUsage = newMessage(
+		"Usage: grep [options] <pattern> [<file>...]\n"+
+			"       grep [-i] --tui <file>...\n"+
+			"       grep secrets [options] [<file>...]",
+		"使い方: grep [オプション] <パターン> [<ファイル>...]\n"+
+			"        grep [-i] --tui <ファイル>...\n"+
+			"        grep secrets [オプション] [<ファイル>...]",
+	)
+	msgOptions           = newMessage("Options:", "オプション:")
+	msgDefault           = newMessage("(default %s)", "(デフォルト %s)")
+	msgError             = newMessage("Error:", "エラー:")
+	msgErrorReadingFile  = newMessage("Error reading file:", "ファイル読み込みエラー:")
+	msgInvalidColor      = newMessage("Error: invalid --color value:", "エラー: --color の値が不正です:")
+	msgInvalidMaxMemory  = newMessage("Error: invalid --max-memory value:", "エラー: --max-memory の値が不正です:")
+	msgInvalidFormatPer  = newMessage("Error: invalid --format-per value:", "エラー: --format-per の値が不正です:")
+	msgInvalidStringsMin = newMessage("Error: invalid --strings-min value:", "エラー: --strings-min の値が不正です:")
+)
+
+// Help for the search flags.
+var (
+	msgFlagAlias      = newMessage("alias for --%s", "--%s の別名")
+	msgFlagIgnoreCase = newMessage("ignore case distinctions", "大文字と小文字を区別しない")
+	msgFlagTUI        = newMessage(
+		"search interactively, updating results as you type",
+		"対話的に検索し、入力に合わせて結果を更新する",
+	)
+	msgFlagHyperlinkFormat = newMessage(
+		"link paths with OSC 8 when writing to a terminal: default, file, vscode, vscode-insiders,\n"+
+			"cursor, kitty, macvim, textmate, none, or a template using {path}, {line}, {column} and {host}",
+		"端末に出力するときパスを OSC 8 でリンクにする: default, file, vscode, vscode-insiders,\n"+
+			"cursor, kitty, macvim, textmate, none、または {path}, {line}, {column}, {host} を使うテンプレート",
+	)
+	msgFlagPager = newMessage(
+		"page output through $MYGREP_PAGER, $PAGER or \"less -R\" when it does not fit on the terminal",
+		"出力が端末に収まらないとき $MYGREP_PAGER、$PAGER または \"less -R\" でページ送りする",
+	)
+	msgFlagLineNumbers = newMessage("show line numbers", "行番号を表示する")
+	msgFlagColor       = newMessage(
+		"colour paths, line numbers and matches: never, auto or always",
+		"パス、行番号、マッチを色付けする: never, auto または always",
+	)
+	msgFlagHeading   = newMessage("show each file name once above its matches", "ファイル名をマッチの上に一度だけ表示する")
+	msgFlagNoHeading = newMessage(
+		"show the file name on every matching line (default)",
+		"マッチした行ごとにファイル名を表示する (デフォルト)",
+	)
+	msgFlagPretty  = newMessage("shortcut for --color always --heading -n", "--color always --heading -n の短縮形")
+	msgFlagAfter   = newMessage("show `NUM` lines of context after each match", "各マッチの後の `NUM` 行も表示する")
+	msgFlagBefore  = newMessage("show `NUM` lines of context before each match", "各マッチの前の `NUM` 行も表示する")
+	msgFlagContext = newMessage("show `NUM` lines of context around each match", "各マッチの前後 `NUM` 行も表示する")
+	msgFlagFormat  = newMessage(
+		"write each result with a Go text/template over .Path, .Line, .Column, .ByteOffset, .Text,\n"+
+			".Match, .Decoded, .Before, .After and .Location; helpers: csv, shell, json",
+		"各結果を Go の text/template で出力する。値は .Path, .Line, .Column, .ByteOffset, .Text,\n"+
+			".Match, .Decoded, .Before, .After, .Location、ヘルパーは csv, shell, json",
+	)
+	msgFlagFormatPer = newMessage(
+		"apply --format per `MODE`: line (each matching line) or match (each match)",
+		"--format を適用する単位 `MODE`: line (マッチした行ごと) または match (マッチごと)",
+	)
+	msgFlagFilesWithMatches = newMessage("print only the names of files with matches", "マッチしたファイルの名前だけを表示する")
+	msgFlagNull             = newMessage(
+		"follow file names with a NUL byte instead of ':' or a newline",
+		"ファイル名の後に ':' や改行の代わりに NUL バイトを出力する",
+	)
+	msgFlagAbsolutePath  = newMessage("show absolute paths", "絶対パスで表示する")
+	msgFlagStripPrefix   = newMessage("show paths below `DIR` relative to it", "`DIR` 以下のパスを DIR からの相対パスで表示する")
+	msgFlagPathSeparator = newMessage(
+		"show paths with `SEP` instead of the OS path separator",
+		"OS のパス区切り文字の代わりに `SEP` を使ってパスを表示する",
+	)
+	msgFlagBaseline      = newMessage("print only matches that are not in the baseline `FILE`", "ベースライン `FILE` にないマッチだけを表示する")
+	msgFlagBaselineFixed = newMessage(
+		"with --baseline, also print baseline matches that are gone",
+		"--baseline と併用し、なくなったベースラインのマッチも表示する",
+	)
+	msgFlagSaveBaseline = newMessage("save all matches as a baseline to `FILE`", "すべてのマッチをベースラインとして `FILE` に保存する")
+	msgFlagRedact       = newMessage(
+		"mask text matching `PATTERN` in the output; repeatable. PATTERN is a regular\n"+
+			"expression or one of the presets email, ipv4, ipv6 and credit-card",
+		"出力中の `PATTERN` に一致する部分を伏せ字にする (複数指定可)。PATTERN は正規表現か、\n"+
+			"プリセット email, ipv4, ipv6, credit-card のいずれか",
+	)
+	msgFlagRegexSizeLimit = newMessage(
+		"reject regular expressions that compile to more than `NUM` instructions",
+		"コンパイル後に `NUM` 命令を超える正規表現を拒否する",
+	)
+	msgFlagMaxMemory = newMessage(
+		"fail a file whose lines, with their context, need more than `SIZE` bytes in memory (suffixes K, M, G);\n"+
+			"documents and notebooks, which are read whole, are limited to 1G without it",
+		"前後行を含めた行の保持に `SIZE` バイトを超えるメモリが必要なファイルをエラーにする (接尾辞 K, M, G)。\n"+
+			"全体を読み込む文書とノートブックは、指定がなければ 1G までとする",
+	)
+	msgFlagRoot = newMessage(
+		"open files only beneath `DIR`, taking relative paths from it and refusing paths and symlinks that leave it",
+		"`DIR` 以下のファイルだけを開く。相対パスは DIR を基準とし、外に出るパスやシンボリックリンクは拒否する",
+	)
+	msgFlagNormalize = newMessage(
+		"match regardless of Unicode normalisation by normalising the pattern and each line to\n"+
+			"`FORM`: nfc (canonical, e.g. NFD text from macOS) or nfkc (also folds compatibility characters)",
+		"パターンと各行を `FORM` に正規化し、Unicode の正規化形式の違いを無視してマッチする:\n"+
+			"nfc (正準等価。macOS の NFD テキストなど) または nfkc (互換文字も同一視する)",
+	)
+	msgFlagWidthFold = newMessage(
+		"treat full-width and half-width forms as equal, e.g. \"ＡＢＣ\" and \"ABC\", \"ｶﾀｶﾅ\" and \"カタカナ\"",
+		"全角と半角を同一視する (例: 「ＡＢＣ」と「ABC」、「ｶﾀｶﾅ」と「カタカナ」)",
+	)
+	msgFlagKanaFold = newMessage(
+		"treat hiragana and katakana as equal, e.g. \"かたかな\" and \"カタカナ\"",
+		"ひらがなとカタカナを同一視する (例: 「かたかな」と「カタカナ」)",
+	)
+	msgFlagColumn     = newMessage("show the column of the first match on each line", "各行の最初のマッチの桁位置を表示する")
+	msgFlagColumnUnit = newMessage(
+		"count --column, --max-columns and .Column in `UNIT`: byte, codepoint, grapheme (user-perceived\n"+
+			"characters) or display (terminal cells, with wide CJK characters and emoji taking two)",
+		"--column、--max-columns、.Column を数える単位 `UNIT`: byte、codepoint、grapheme (見た目の 1 文字)\n"+
+			"または display (端末のセル数。全角文字や絵文字は 2)",
+	)
+	msgFlagMaxColumns = newMessage(
+		"cut lines longer than `NUM` columns, keeping the first match in view",
+		"`NUM` 桁を超える行を、最初のマッチが見えるように切り詰める",
+	)
+	msgFlagNotebookOutputs = newMessage(
+		"also search the outputs of Jupyter notebook cells (text only; images are skipped)",
+		"Jupyter ノートブックのセルの出力も検索する (テキストのみ。画像は対象外)",
+	)
+	msgFlagDecode = newMessage(
+		"also match inside encoded tokens in each line, decoding them as each of `LIST`, a comma-separated\n"+
+			"list of base64, url and hex, and showing what a matching token decodes to",
+		"各行のエンコードされたトークンを `LIST` (base64、url、hex のカンマ区切り) の各形式でデコードして\n"+
+			"その中もマッチの対象とし、マッチしたトークンのデコード結果を表示する",
+	)
+	msgFlagHex = newMessage(
+		"take the pattern as bytes in hex, e.g. \"DE AD ?? EF\", with ?? or D? for any byte or nibble and\n"+
+			"[2-4] to skip 2 to 4 bytes (at most 4096); hits are shown as hexdumps, with -A, -B and -C counting bytes",
+		"パターンを 16 進のバイト列として扱う (例: \"DE AD ?? EF\")。?? や D? は任意のバイトやニブル、\n"+
+			"[2-4] は 2〜4 バイトの読み飛ばし (最大 4096)。ヒットは 16 進ダンプで表示し、-A、-B、-C はバイト数を表す",
+	)
+	msgFlagStrings = newMessage(
+		"search only runs of printable characters, like strings(1), showing where each run is:\n"+
+			"the section and virtual address in ELF files, the byte offset in others",
+		"strings(1) のように印字可能な文字の並びだけを検索し、その位置を表示する:\n"+
+			"ELF ファイルではセクションと仮想アドレス、それ以外ではバイトオフセット",
+	)
+	msgFlagStringsMin = newMessage("with --strings, the minimum `NUM` of characters in a run", "--strings で対象とする文字の並びの最小文字数 `NUM`")
+	msgFlagTextMode   = newMessage(
+		"how to read lines that are not valid UTF-8, as `MODE`: utf8 (each invalid byte reads, matches\n"+
+			"and prints as \\xNN, and \\xNN in the pattern matches only such a byte) or bytes (match raw\n"+
+			"bytes; -i folds ASCII letters only)",
+		"UTF-8 として不正な行の扱い `MODE`: utf8 (不正なバイトは \\xNN として読み、マッチし、表示する。\n"+
+			"パターン中の \\xNN はそのバイトにのみマッチする) または bytes (生のバイト列でマッチする。\n"+
+			"-i は ASCII の英字のみ同一視する)",
+	)
+	msgFlagPassthru = newMessage(
+		"print all lines, highlighting matches and marking them with ':' instead of '-'",
+		"すべての行を表示し、マッチを強調して '-' の代わりに ':' で示す",
+	)
+)
+
+// The secrets subcommand.
+var (
+	msgSecretsUsage = newMessage(
+		"Usage: grep secrets [options] [<file>...]",
+		"使い方: grep secrets [オプション] [<ファイル>...]",
+	)
+	msgSecretsAbout = newMessage(
+		"Scan files for credentials. Secrets are always printed masked.\n"+
+			"Lines containing \"%s\" are skipped.",
+		"ファイルから認証情報を探します。シークレットは常に伏せ字で表示されます。\n"+
+			"\"%s\" を含む行はスキップされます。",
+	)
+	msgFlagAllowlist = newMessage(
+		"skip secrets matching any regular expression in `FILE`",
+		"`FILE` 内のいずれかの正規表現に一致するシークレットをスキップする",
+	)
+	msgFlagRules       = newMessage("list the built-in rules and exit", "組み込みルールを一覧表示して終了する")
+	msgFlagSecretsRoot = newMessage(
+		"scan files only beneath `DIR`, as for the search command",
+		"検索コマンドと同様に `DIR` 以下のファイルだけを調べる",
+	)
+)
+
+// Errors.
+var (
+	msgInvalidBaseline      = newMessage("invalid baseline %s: %w", "ベースライン %s が不正です: %w")
+	msgBaselineVersion      = newMessage("invalid baseline %s: unsupported version %d", "ベースライン %s が不正です: 未対応のバージョン %d です")
+	msgBaselinePattern      = newMessage("baseline %s was saved for pattern %q, not %q", "ベースライン %s はパターン %q で保存されたもので、%q ではありません")
+	msgBaselineFixed        = newMessage("fixed: %s:%d:%s", "解消: %s:%d:%s")
+	msgHyperlinkNoPath      = newMessage("invalid hyperlink format %q: must contain {path} or be one of the presets", "ハイパーリンク形式 %q が不正です: {path} を含むか、プリセットのいずれかにしてください")
+	msgHyperlinkUnclosed    = newMessage("invalid hyperlink format %q: unclosed {", "ハイパーリンク形式 %q が不正です: { が閉じられていません")
+	msgHyperlinkUnknown     = newMessage("invalid hyperlink format %q: unknown variable {%s}", "ハイパーリンク形式 %q が不正です: 不明な変数 {%s} です")
+	msgLimitExceeded        = newMessage("exceeds --%s %s", "--%s の上限 %s を超えています")
+	msgLimitInstructions    = newMessage("%d instructions (it needs %d)", "%d 命令 (必要なのは %d 命令)")
+	msgLineLimit            = newMessage("%s: line %d %w", "%s: %d 行目が %w")
+	msgDocumentLimit        = newMessage("%s: decompressed size %w", "%s: 展開後のサイズが %w")
+	msgNotebookLimit        = newMessage("%s: size %w", "%s: サイズが %w")
+	msgInvalidSize          = newMessage("invalid size %q", "サイズ %q が不正です")
+	msgInvalidPathSeparator = newMessage("invalid --path-separator %q: must be a single byte", "--path-separator %q が不正です: 1 バイトにしてください")
+	msgInvalidRedact        = newMessage("invalid --redact pattern %q: %w", "--redact のパターン %q が不正です: %w")
+	msgOutsideRoot          = newMessage("path is outside --root", "パスが --root の外にあります")
+	msgInvalidRoot          = newMessage("invalid --root: %w", "--root が不正です: %w")
+	msgRootNotDir           = newMessage("invalid --root: %s is not a directory", "--root が不正です: %s はディレクトリではありません")
+	msgInvalidNormalize     = newMessage("invalid --normalize form %q: must be nfc or nfkc", "--normalize の形式 %q が不正です: nfc または nfkc にしてください")
+	msgInvalidColumnUnit    = newMessage("invalid --column-unit %q: must be byte, codepoint, grapheme or display", "--column-unit %q が不正です: byte、codepoint、grapheme、display のいずれかにしてください")
+	msgInvalidTextMode      = newMessage("invalid --text-mode %q: must be utf8 or bytes", "--text-mode %q が不正です: utf8 または bytes にしてください")
+	msgTextModeBytes        = newMessage("--text-mode bytes cannot be combined with --normalize, --width-fold or --kana-fold", "--text-mode bytes は --normalize、--width-fold、--kana-fold と併用できません")
+	msgInvalidDocument      = newMessage("%s: not a readable Office document: %w", "%s: Office 文書として読み込めません: %w")
+	msgInvalidNotebook      = newMessage("%s: not a readable Jupyter notebook: %w", "%s: Jupyter ノートブックとして読み込めません: %w")
+	msgInvalidDecode        = newMessage("invalid --decode encoding %q: must be base64, url or hex", "--decode の形式 %q が不正です: base64、url、hex のいずれかにしてください")
+	msgDecoded              = newMessage("[%s: %q]", "[%s: %q]")
+	msgInvalidHex           = newMessage("invalid --hex pattern %q: cannot parse %q", "--hex のパターン %q が不正です: %q を解釈できません")
+	msgHexJumpTooWide       = newMessage("invalid --hex pattern %q: jumps may skip at most %d bytes", "--hex のパターン %q が不正です: ジャンプで飛ばせるのは %d バイトまでです")
+	msgMissingPart          = newMessage("required part is missing", "必要なパートがありません")
+	msgInvalidFormat        = newMessage("invalid --format: %w", "--format が不正です: %w")
+	msgNoTerm               = newMessage("terminal control is not supported on %s", "%s では端末制御に対応していません")
+	msgTUIReadingFile       = newMessage("reading file: %w", "ファイルを読み込めません: %w")
+	msgTUIMatches           = newMessage("%d matches", "%d 件")
+	msgTUISearching         = newMessage("searching...", "検索中...")
+)
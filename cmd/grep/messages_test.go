@@ -0,0 +1,27 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// verb matches a fmt verb, with its flags, width, precision and argument
+// index.
+var verb = regexp.MustCompile(`%[-+# 0]*(?:\[\d+\])?(?:\d+|\*)?(?:\.(?:\d+|\*)?)?(?:\[\d+\])?[a-zA-Z%]`)
+
+func TestMessages(t *testing.T) {
+	if len(messages) == 0 {
+		t.Fatal("no messages are registered")
+	}
+	for _, m := range messages {
+		if m.en == "" || m.ja == "" {
+			t.Errorf("message %q / %q is missing a translation", m.en, m.ja)
+			continue
+		}
+		en, ja := verb.FindAllString(m.en, -1), verb.FindAllString(m.ja, -1)
+		if !slices.Equal(en, ja) {
+			t.Errorf("message %q has verbs %q, but its translation %q has %q", m.en, en, m.ja, ja)
+		}
+	}
+}
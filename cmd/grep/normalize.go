@@ -0,0 +1,222 @@
+package main
+
+//go:generate python3 normtables_gen.py
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// normForm is a Unicode normalisation form for --normalize. Both the
+// pattern and each line are normalised before matching, so that text in
+// NFD, as macOS writes it, matches a pattern typed in NFC.
+type normForm struct {
+	compat bool // NFKC rather than NFC
+}
+
+func parseNormForm(name string) (*normForm, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "nfc":
+		return &normForm{}, nil
+	case "nfkc":
+		return &normForm{compat: true}, nil
+	}
+	return nil, msgInvalidNormalize.errorf(name)
+}
+
+// normTables holds normtables.go parsed, which only --normalize and
+// --width-fold need.
+var normTables struct {
+	once   sync.Once
+	ccc    map[rune]uint8
+	canon  map[rune][]rune
+	compat map[rune][]rune
+	pairs  map[[2]rune]rune
+	width  map[rune]rune
+}
+
+func loadNormTables() {
+	t := &normTables
+	t.ccc = make(map[rune]uint8)
+	for _, e := range strings.Fields(normCCCData) {
+		cp, class, _ := strings.Cut(e, ":")
+		n, _ := strconv.Atoi(class)
+		t.ccc[parseHexRunes(cp)[0]] = uint8(n)
+	}
+	t.canon = parseDecompositions(normCanonData)
+	t.compat = parseDecompositions(normCompatData)
+	t.pairs = make(map[[2]rune]rune)
+	for _, e := range strings.Fields(normPairData) {
+		pair, composite, _ := strings.Cut(e, ":")
+		rs := parseHexRunes(pair)
+		t.pairs[[2]rune{rs[0], rs[1]}] = parseHexRunes(composite)[0]
+	}
+	t.width = make(map[rune]rune)
+	for _, e := range strings.Fields(normWidthData) {
+		cp, counterpart, _ := strings.Cut(e, ":")
+		t.width[parseHexRunes(cp)[0]] = parseHexRunes(counterpart)[0]
+	}
+}
+
+func parseDecompositions(data string) map[rune][]rune {
+	m := make(map[rune][]rune)
+	for _, e := range strings.Fields(data) {
+		cp, d, _ := strings.Cut(e, ":")
+		m[parseHexRunes(cp)[0]] = parseHexRunes(d)
+	}
+	return m
+}
+
+// parseHexRunes parses comma-separated hexadecimal code points.
+func parseHexRunes(s string) []rune {
+	var rs []rune
+	for _, h := range strings.Split(s, ",") {
+		n, _ := strconv.ParseUint(h, 16, 32)
+		rs = append(rs, rune(n))
+	}
+	return rs
+}
+
+// Hangul syllables are decomposed and composed arithmetically.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// normRune is a rune of normalised text with the byte range of the
+// original text it came from. r is -1 for a byte of invalid UTF-8, which
+// is kept as it is.
+type normRune struct {
+	r      rune
+	lo, hi int
+}
+
+// apply returns s in form f, and for each byte of the result the range of
+// bytes of s it came from. spans is nil if s is unchanged.
+func (f *normForm) apply(s string) (string, [][2]int) {
+	if isASCII(s) {
+		return s, nil
+	}
+	normTables.once.Do(loadNormTables)
+
+	// Decompose, then put combining marks in canonical order.
+	var rs []normRune
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			rs = append(rs, normRune{-1, i, i + 1})
+		} else {
+			for _, d := range f.decompose(r) {
+				rs = append(rs, normRune{d, i, i + size})
+			}
+		}
+		i += size
+	}
+	for i := 1; i < len(rs); i++ {
+		for j := i; j > 0 && combiningClass(rs[j].r) != 0 &&
+			combiningClass(rs[j-1].r) > combiningClass(rs[j].r); j-- {
+			rs[j], rs[j-1] = rs[j-1], rs[j]
+		}
+	}
+	rs = compose(rs)
+
+	var b strings.Builder
+	spans := make([][2]int, 0, len(s))
+	for _, nr := range rs {
+		n := b.Len()
+		if nr.r < 0 {
+			b.WriteByte(s[nr.lo])
+		} else {
+			b.WriteRune(nr.r)
+		}
+		for ; n < b.Len(); n++ {
+			spans = append(spans, [2]int{nr.lo, nr.hi})
+		}
+	}
+	return b.String(), spans
+}
+
+// decompose returns the full decomposition of r.
+func (f *normForm) decompose(r rune) []rune {
+	if s := r - hangulSBase; s >= 0 && s < hangulSCount {
+		l := hangulLBase + s/hangulNCount
+		v := hangulVBase + s%hangulNCount/hangulTCount
+		if t := hangulTBase + s%hangulTCount; t != hangulTBase {
+			return []rune{l, v, t}
+		}
+		return []rune{l, v}
+	}
+	if f.compat {
+		if d, ok := normTables.compat[r]; ok {
+			return d
+		}
+	}
+	if d, ok := normTables.canon[r]; ok {
+		return d
+	}
+	return []rune{r}
+}
+
+func combiningClass(r rune) uint8 {
+	return normTables.ccc[r]
+}
+
+// compose applies canonical composition to decomposed, ordered runes.
+func compose(rs []normRune) []normRune {
+	out := rs[:0]
+	starter := -1   // index in out of the last starter
+	lastClass := -1 // class of the last rune after the starter, or -1
+	for _, nr := range rs {
+		class := int(combiningClass(nr.r))
+		if starter >= 0 && nr.r >= 0 && (lastClass < 0 || lastClass < class) {
+			if c, ok := composePair(out[starter].r, nr.r); ok {
+				out[starter].r = c
+				out[starter].lo = min(out[starter].lo, nr.lo)
+				out[starter].hi = max(out[starter].hi, nr.hi)
+				continue
+			}
+		}
+		if class == 0 {
+			starter, lastClass = len(out), -1
+		} else {
+			lastClass = class
+		}
+		out = append(out, nr)
+	}
+	return out
+}
+
+func composePair(a, b rune) (rune, bool) {
+	if l := a - hangulLBase; l >= 0 && l < hangulLCount {
+		if v := b - hangulVBase; v >= 0 && v < hangulVCount {
+			return hangulSBase + (l*hangulVCount+v)*hangulTCount, true
+		}
+	}
+	if s := a - hangulSBase; s >= 0 && s < hangulSCount && s%hangulTCount == 0 {
+		if t := b - hangulTBase; t > 0 && t < hangulTCount {
+			return a + t, true
+		}
+	}
+	c, ok := normTables.pairs[[2]rune{a, b}]
+	return c, ok
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
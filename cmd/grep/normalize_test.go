@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	nfc, nfkc := &normForm{}, &normForm{compat: true}
+	tests := []struct {
+		form    *normForm
+		in, out string
+	}{
+		{nfc, "plain ascii", "plain ascii"},
+		{nfc, "cafe\u0301", "café"},
+		{nfc, "café", "café"},
+		{nfc, "\u1112\u1161\u11ab\u1100\u116e\u11a8", "한국"}, // Hangul jamo
+		{nfc, "\u1112\u1161", "하"},
+		{nfc, "ｶﾞ", "ｶﾞ"},                 // halfwidth kana are left alone
+		{nfc, "a\u0323\u0307", "ạ\u0307"}, // marks are put in canonical order first
+		{nfc, "a\u0307\u0323", "ạ\u0307"},
+		{nfc, "ﬁle", "ﬁle"},
+		{nfkc, "ﬁle", "file"},
+		{nfkc, "ＡＢＣ１２３", "ABC123"},
+		{nfkc, "ｶﾞ", "ガ"},
+		{nfkc, "㍿", "株式会社"},
+		{nfc, "bad \xff byte", "bad \xff byte"},
+	}
+	for _, tt := range tests {
+		if got, _ := tt.form.apply(tt.in); got != tt.out {
+			t.Errorf("apply(%+q) with compat=%v = %+q, want %+q", tt.in, tt.form.compat, got, tt.out)
+		}
+	}
+}
+
+func TestNormalizeSpans(t *testing.T) {
+	text, spans := (&normForm{}).apply("xe\u0301y")
+	if text != "xéy" {
+		t.Fatalf("text = %q", text)
+	}
+	// é is two bytes, both from the e and the combining mark after it.
+	want := [][2]int{{0, 1}, {1, 4}, {1, 4}, {4, 5}}
+	if fmt.Sprint(spans) != fmt.Sprint(want) {
+		t.Errorf("spans = %v, want %v", spans, want)
+	}
+	if _, spans := (&normForm{}).apply("ascii"); spans != nil {
+		t.Errorf("ascii spans = %v, want nil", spans)
+	}
+}
+
+// Matches in normalised text are reported at the bytes of the line they
+// came from.
+func TestNormalizeMatches(t *testing.T) {
+	tests := []struct {
+		compat        bool
+		pattern, line string
+		want          [][2]int
+	}{
+		{false, "café", "a cafe\u0301 b", [][2]int{{2, 8}}},
+		{false, "cafe\u0301", "a café b", [][2]int{{2, 7}}},
+		{false, "한", "\u1112\u1161\u11ab\u1100\u116e\u11a8", [][2]int{{0, 9}}},
+		{false, "file", "ﬁle", nil},
+		{true, "file", "a ﬁle", [][2]int{{2, 7}}},
+		{true, "fi", "ﬁﬁ", [][2]int{{0, 3}, {3, 6}}},
+		// "i" is half of ﬁ: the match grows to the whole character.
+		{true, "ile", "ﬁle", [][2]int{{0, 5}}},
+		{true, "ABC", "x ＡＢＣ", [][2]int{{2, 11}}},
+	}
+	for _, tt := range tests {
+		m := newMatcher(tt.pattern, false, modeUTF8, &normForm{compat: tt.compat})
+		got := m.findAll(tt.line, nil)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q in %q: got %v, want %v", tt.pattern, tt.line, got, tt.want)
+		}
+	}
+}
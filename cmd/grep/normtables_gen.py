@@ -0,0 +1,70 @@
+#!/usr/bin/env python3
+"""Generate normtables.go, the Unicode normalisation data for --normalize.
+
+Run with `go generate ./cmd/grep`. The data comes from Python's unicodedata
+module, so the Unicode version follows the Python used to run this script.
+"""
+
+import sys
+import unicodedata
+
+HANGUL_FIRST, HANGUL_LAST = 0xAC00, 0xD7A3
+
+
+def chars():
+    for cp in range(sys.maxunicode + 1):
+        if HANGUL_FIRST <= cp <= HANGUL_LAST or 0xD800 <= cp <= 0xDFFF:
+            continue  # Hangul is algorithmic; surrogates are not characters
+        yield cp, chr(cp)
+
+
+def hexes(s):
+    return ",".join("%x" % ord(c) for c in s)
+
+
+def main():
+    ccc, canon, compat, pairs, width = [], [], [], [], []
+    for cp, c in chars():
+        if unicodedata.combining(c):
+            ccc.append("%x:%d" % (cp, unicodedata.combining(c)))
+        nfd = unicodedata.normalize("NFD", c)
+        nfkd = unicodedata.normalize("NFKD", c)
+        if nfd != c:
+            canon.append("%x:%s" % (cp, hexes(nfd)))
+        if nfkd != nfd:
+            compat.append("%x:%s" % (cp, hexes(nfkd)))
+
+        # Width variants fold to their counterpart: full-width ASCII to
+        # ASCII, half-width katakana to full-width.
+        d = unicodedata.decomposition(c)
+        if d.startswith("<wide>") or d.startswith("<narrow>"):
+            width.append("%x:%x" % (cp, int(d.split()[1], 16)))
+
+        # Primary composites: canonical pairs that NFC puts back together.
+        if d and not d.startswith("<"):
+            parts = [chr(int(x, 16)) for x in d.split()]
+            if len(parts) == 2 and unicodedata.normalize("NFC", "".join(parts)) == c:
+                pairs.append("%x,%x:%x" % (ord(parts[0]), ord(parts[1]), cp))
+
+    with open("normtables.go", "w") as f:
+        f.write("// Code generated by normtables_gen.py; DO NOT EDIT.\n\n")
+        f.write("package main\n\n")
+        f.write("// normUnicodeVersion is the Unicode version of the tables.\n")
+        f.write('const normUnicodeVersion = "%s"\n\n' % unicodedata.unidata_version)
+        tables = [
+            ("normCCCData", "code points with a non-zero canonical combining class, as cp:class", ccc),
+            ("normCanonData", "full canonical decompositions, as cp:cp,cp...", canon),
+            ("normCompatData", "full compatibility decompositions where they differ from the canonical ones", compat),
+            ("normPairData", "primary composites, as first,second:composite", pairs),
+            ("normWidthData", "full-width and half-width forms with their counterparts, as cp:cp", width),
+        ]
+        blocks = []
+        for name, doc, entries in tables:
+            lines = [" ".join(entries[i:i + 12]) for i in range(0, len(entries), 12)]
+            blocks.append("// %s lists %s.\nconst %s = \"\" +\n%s\n" % (
+                name, doc, name, " +\n".join('\t"%s "' % line for line in lines)))
+        f.write("\n".join(blocks))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func isNotebook(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".ipynb")
+}
+
+// nbText is notebook text, which nbformat stores either as one string or
+// as a list of lines.
+type nbText string
+
+func (t *nbText) UnmarshalJSON(b []byte) error {
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err == nil {
+		*t = nbText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*t = nbText(s)
+	return nil
+}
+
+// notebook is as much of an nbformat 4 notebook as the search needs.
+// Outputs are decoded only for --notebook-outputs, and then only their
+// text, since rich outputs hold arbitrary JSON such as widget state.
+type notebook struct {
+	Cells []struct {
+		Source  nbText            `json:"source"`
+		Outputs []json.RawMessage `json:"outputs"`
+	} `json:"cells"`
+}
+
+// nbOutput is a cell output, with the values left raw until needed.
+type nbOutput struct {
+	Type  string                     `json:"output_type"`
+	Text  json.RawMessage            `json:"text"` // stream
+	Data  map[string]json.RawMessage `json:"data"` // execute_result, display_data
+	Name  json.RawMessage            `json:"ename"`
+	Value json.RawMessage            `json:"evalue"`
+}
+
+// text returns the searchable text of the output, or "" if there is none.
+func (o *nbOutput) text() string {
+	switch o.Type {
+	case "stream":
+		return rawText(o.Text)
+	case "execute_result", "display_data":
+		return rawText(o.Data["text/plain"])
+	case "error":
+		return rawText(o.Name) + ": " + rawText(o.Value)
+	}
+	return ""
+}
+
+// rawText decodes notebook text, ignoring values that are not text.
+func rawText(raw json.RawMessage) string {
+	var t nbText
+	if len(raw) == 0 || json.Unmarshal(raw, &t) != nil {
+		return ""
+	}
+	return string(t)
+}
+
+// searchNotebook searches the cells of the Jupyter notebook f, reporting
+// matches as path:cell N:line L, and with --notebook-outputs also the
+// outputs of each cell as path:cell N output M:line L. Only the text/plain
+// form of rich outputs is searched, so images and HTML are skipped.
+func searchNotebook(p printer, m *matcher, name string, f *os.File, opts *searchOptions) error {
+	limit, limitErr := opts.documentLimit()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return msgNotebookLimit.errorf(name, limitErr)
+	}
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return msgInvalidNotebook.errorf(name, err)
+	}
+	var parts []docPart
+	for i, c := range nb.Cells {
+		cell := "cell " + strconv.Itoa(i+1)
+		parts = append(parts, docPart{location: cell, text: strings.TrimSuffix(string(c.Source), "\n")})
+		if !opts.notebookOutputs {
+			continue
+		}
+		for j, raw := range c.Outputs {
+			var o nbOutput
+			if err := json.Unmarshal(raw, &o); err != nil {
+				return msgInvalidNotebook.errorf(name, err)
+			}
+			if text := o.text(); text != "" {
+				parts = append(parts, docPart{
+					location: cell + " output " + strconv.Itoa(j+1),
+					text:     strings.TrimSuffix(text, "\n"),
+				})
+			}
+		}
+	}
+	return searchParts(p, m, name, parts, opts)
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isOfficeDocument reports whether name is an Office Open XML document
+// whose text is searched instead of its bytes.
+func isOfficeDocument(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx", ".xlsx", ".pptx":
+		return true
+	}
+	return false
+}
+
+// docPart is text extracted from a document. The line printers show its
+// location after the path, e.g. "plan.xlsx[Budget!B3]" or "deck.pptx[slide 2]".
+type docPart struct {
+	location string // "" for the body of a Word document
+	text     string
+}
+
+// searchDocument searches the text of the Office document f. Lines are
+// paragraphs and line breaks within them, numbered within their part.
+func searchDocument(p printer, m *matcher, name string, f *os.File, opts *searchOptions) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return msgInvalidDocument.errorf(name, err)
+	}
+	limit, limitErr := opts.documentLimit()
+	doc := &document{Reader: zr, left: limit}
+	var parts []docPart
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx":
+		parts, err = wordParts(doc)
+	case ".xlsx":
+		parts, err = sheetParts(doc)
+	case ".pptx":
+		parts, err = slideParts(doc)
+	}
+	if errors.Is(err, errDocumentTooLarge) {
+		return msgDocumentLimit.errorf(name, limitErr)
+	}
+	if err != nil {
+		return msgInvalidDocument.errorf(name, err)
+	}
+	return searchParts(p, m, name, parts, opts)
+}
+
+// searchParts searches each part of the document name in turn.
+func searchParts(p printer, m *matcher, name string, parts []docPart, opts *searchOptions) error {
+	for _, part := range parts {
+		if err := searchReader(p, m, name, part.location, strings.NewReader(part.text), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func wordParts(doc *document) ([]docPart, error) {
+	f := doc.file("word/document.xml")
+	if f == nil {
+		return nil, errMissingPart
+	}
+	paras, err := doc.paragraphs(f, "p")
+	if err != nil {
+		return nil, err
+	}
+	return []docPart{{text: strings.Join(paras, "\n")}}, nil
+}
+
+func slideParts(doc *document) ([]docPart, error) {
+	// Slides are named slide1.xml, slide2.xml, ... in presentation order
+	// unless they have been reordered, which is rare enough to ignore.
+	type slide struct {
+		n int
+		f *zip.File
+	}
+	var slides []slide
+	for _, f := range doc.File {
+		base, ok := strings.CutPrefix(f.Name, "ppt/slides/slide")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(base, ".xml")); err == nil {
+			slides = append(slides, slide{n, f})
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].n < slides[j].n })
+
+	var parts []docPart
+	for _, s := range slides {
+		paras, err := doc.paragraphs(s.f, "p")
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, docPart{location: "slide " + strconv.Itoa(s.n), text: strings.Join(paras, "\n")})
+	}
+	return parts, nil
+}
+
+// Parts of a spreadsheet, as much of them as the search needs.
+type (
+	xlsxWorkbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxRels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxSheet struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string   `xml:"t"`
+				Runs []string `xml:"r>t"`
+			} `xml:"is"`
+		} `xml:"sheetData>row>c"`
+	}
+)
+
+// sheetParts returns one part per non-empty cell, in sheet order.
+func sheetParts(doc *document) ([]docPart, error) {
+	var strs []string
+	if f := doc.file("xl/sharedStrings.xml"); f != nil {
+		var err error
+		if strs, err = doc.paragraphs(f, "si"); err != nil {
+			return nil, err
+		}
+	}
+	var wb xlsxWorkbook
+	if err := doc.decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels xlsxRels
+	if err := doc.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for _, r := range rels.Rels {
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+
+	var parts []docPart
+	for _, s := range wb.Sheets {
+		var sheet xlsxSheet
+		if err := doc.decode(targets[s.ID], &sheet); err != nil {
+			return nil, err
+		}
+		for _, c := range sheet.Cells {
+			text := c.Value
+			switch c.Type {
+			case "s":
+				i, err := strconv.Atoi(c.Value)
+				if err != nil || i < 0 || i >= len(strs) {
+					continue
+				}
+				text = strs[i]
+			case "inlineStr":
+				text = c.Inline.Text + strings.Join(c.Inline.Runs, "")
+			}
+			if text != "" {
+				parts = append(parts, docPart{location: s.Name + "!" + c.Ref, text: text})
+			}
+		}
+	}
+	return parts, nil
+}
+
+// errMissingPart is returned for a document without the part its type
+// requires.
+var errMissingPart = errors.New(msgMissingPart.String())
+
+// errDocumentTooLarge is returned once the parts of a document read take
+// more than its limit.
+var errDocumentTooLarge = errors.New("document over memory budget")
+
+// document is an Office document being read. Its parts are decompressed
+// against one budget, so that a small archive cannot expand to fill memory.
+type document struct {
+	*zip.Reader
+	left int64 // bytes that may still be decompressed
+}
+
+func (d *document) file(name string) *zip.File {
+	for _, f := range d.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// open opens f to be read within the budget of d.
+func (d *document) open(f *zip.File) (io.ReadCloser, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &budgetReader{ReadCloser: r, left: &d.left}, nil
+}
+
+func (d *document) decode(name string, v any) error {
+	f := d.file(name)
+	if f == nil {
+		return errMissingPart
+	}
+	r, err := d.open(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// budgetReader fails with errDocumentTooLarge once it would read more than
+// *left bytes.
+type budgetReader struct {
+	io.ReadCloser
+	left *int64
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if *r.left <= 0 {
+		// Only fail if there is more to read.
+		var probe [1]byte
+		n, err := r.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, errDocumentTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > *r.left {
+		p = p[:*r.left]
+	}
+	n, err := r.ReadCloser.Read(p)
+	*r.left -= int64(n)
+	return n, err
+}
+
+// paragraphs returns the text of each element named para in f, made of
+// the text elements (t) within it. Tabs and breaks become "\t" and "\n";
+// tab stop definitions and the phonetic guides (rPh) on Japanese shared
+// strings are skipped. A paragraph nested in another, as in a text box,
+// comes before the one it is in, whose text goes on around it.
+func (d *document) paragraphs(f *zip.File, para string) ([]string, error) {
+	r, err := d.open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var paras []string
+	var open []*strings.Builder // the paragraphs being read, innermost last
+	inText, skip := false, 0
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return paras, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case para:
+				open = append(open, new(strings.Builder))
+			case "t":
+				inText = true
+			case "tab":
+				if len(open) > 0 && skip == 0 {
+					open[len(open)-1].WriteByte('\t')
+				}
+			case "br", "cr":
+				if len(open) > 0 && skip == 0 {
+					open[len(open)-1].WriteByte('\n')
+				}
+			case "tabs", "tabLst", "rPh":
+				skip++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case para:
+				if len(open) > 0 {
+					paras = append(paras, open[len(open)-1].String())
+					open = open[:len(open)-1]
+				}
+			case "t":
+				inText = false
+			case "tabs", "tabLst", "rPh":
+				skip--
+			}
+		case xml.CharData:
+			if len(open) > 0 && inText && skip == 0 {
+				open[len(open)-1].Write(t)
+			}
+		}
+	}
+}
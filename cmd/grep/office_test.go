@@ -0,0 +1,97 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// zipOf returns a zip archive holding files, keyed by name.
+func zipOf(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func TestWordParts(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{
+			"paragraphs",
+			`<w:p><w:r><w:t>one</w:t></w:r></w:p><w:p><w:r><w:t>two</w:t><w:tab/><w:t>cols</w:t><w:br/><w:t>three</w:t></w:r></w:p>`,
+			[]string{"one", "two\tcols\nthree"},
+		},
+		{
+			"tab stops",
+			`<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>text</w:t></w:r></w:p>`,
+			[]string{"text"},
+		},
+		{
+			"text box",
+			`<w:p><w:r><w:t>outer before </w:t></w:r><w:r><w:pict><v:textbox><w:txbxContent>` +
+				`<w:p><w:r><w:t>inner box</w:t></w:r></w:p>` +
+				`</w:txbxContent></v:textbox></w:pict></w:r><w:r><w:t> outer after needle</w:t></w:r></w:p>`,
+			[]string{"inner box", "outer before  outer after needle"},
+		},
+	}
+	for _, tt := range tests {
+		zr := zipOf(t, map[string]string{
+			"word/document.xml": `<w:document ` + wordNS + ` xmlns:v="urn:schemas-microsoft-com:vml"><w:body>` + tt.body + `</w:body></w:document>`,
+		})
+		parts, err := wordParts(&document{Reader: zr, left: defaultDocumentLimit})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, p := range parts {
+			got = append(got, p.text)
+		}
+		want := []string{strings.Join(tt.want, "\n")}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestWordPartsMissing(t *testing.T) {
+	doc := &document{Reader: zipOf(t, map[string]string{"other.xml": "<x/>"}), left: defaultDocumentLimit}
+	if _, err := wordParts(doc); err != errMissingPart {
+		t.Errorf("got %v, want errMissingPart", err)
+	}
+}
+
+func TestWordPartsTooLarge(t *testing.T) {
+	body := `<w:p><w:r><w:t>` + strings.Repeat("needle ", 1000) + `</w:t></w:r></w:p>`
+	zr := zipOf(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body>` + body + `</w:body></w:document>`,
+	})
+	size := int64(zr.File[0].UncompressedSize64)
+	if _, err := wordParts(&document{Reader: zr, left: size - 1}); !errors.Is(err, errDocumentTooLarge) {
+		t.Errorf("under the size: got %v, want errDocumentTooLarge", err)
+	}
+	if _, err := wordParts(&document{Reader: zr, left: size}); err != nil {
+		t.Errorf("at the size: got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+var errPagerClosed = errors.New("pager closed")
+
+// pagerCommand returns the pager to use: $MYGREP_PAGER, then $PAGER, then
+// "less -R" so that colours survive.
+func pagerCommand() string {
+	if cmd := os.Getenv("MYGREP_PAGER"); cmd != "" {
+		return cmd
+	}
+	if cmd := os.Getenv("PAGER"); cmd != "" {
+		return cmd
+	}
+	return "less -R"
+}
+
+// pagerWriter holds back output until it no longer fits on the terminal, and
+// only then starts the pager and streams everything through it.
+type pagerWriter struct {
+	out     *os.File
+	command string
+	rows    int
+
+	buf   bytes.Buffer
+	lines int
+
+	cmd    *exec.Cmd
+	pipe   io.WriteCloser
+	direct bool // the pager could not be started
+	closed bool // the pager exited before reading all output
+}
+
+func newPagerWriter(out *os.File, command string, rows int) *pagerWriter {
+	return &pagerWriter{out: out, command: command, rows: rows}
+}
+
+func (w *pagerWriter) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, errPagerClosed
+	}
+	if w.pipe != nil {
+		return w.writePipe(b)
+	}
+	if w.direct {
+		return w.out.Write(b)
+	}
+
+	w.buf.Write(b)
+	w.lines += bytes.Count(b, []byte("\n"))
+	if w.lines < w.rows {
+		return len(b), nil
+	}
+
+	if err := w.start(); err != nil {
+		// Fall back to writing to the terminal directly.
+		w.direct = true
+		_, err := w.buf.WriteTo(w.out)
+		return len(b), err
+	}
+	if _, err := w.writePipe(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	w.buf.Reset()
+	return len(b), nil
+}
+
+func (w *pagerWriter) start() error {
+	cmd := exec.Command("sh", "-c", w.command)
+	cmd.Stdout, cmd.Stderr = w.out, os.Stderr
+	// Keep colours when the user's pager is a plain "less".
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=R")
+	}
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// The pager handles Ctrl-C itself; dying here would leave it reading a
+	// closed pipe with the terminal in its hands.
+	signal.Ignore(os.Interrupt)
+	w.cmd, w.pipe = cmd, pipe
+	return nil
+}
+
+// writePipe writes to the pager, turning a broken pipe into errPagerClosed
+// since quitting the pager early is not an error.
+func (w *pagerWriter) writePipe(b []byte) (int, error) {
+	n, err := w.pipe.Write(b)
+	if err != nil {
+		w.closed = true
+		return n, errPagerClosed
+	}
+	return n, nil
+}
+
+// Close flushes output that never filled the screen, or waits for the pager
+// to exit.
+func (w *pagerWriter) Close() error {
+	if w.pipe == nil {
+		_, err := w.buf.WriteTo(w.out)
+		return err
+	}
+	w.pipe.Close()
+	w.cmd.Wait() // the pager's exit status is not ours
+	return nil
+}
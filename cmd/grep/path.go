@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// stdinName is how standard input is shown in place of a path.
+const stdinName = "(standard input)"
+
+// pathFormat controls how file paths are shown in the output.
+type pathFormat struct {
+	absolute    bool     // show absolute paths
+	stripPrefix string   // absolute directory to show paths relative to
+	separator   string   // replaces the OS path separator if set
+	root        *sandbox // where relative paths are opened from, if set
+
+	// Paths repeat on every matching line, so remember the last one.
+	lastPath, lastDisplay string
+}
+
+// newPathFormat returns the path format for the flags. Relative paths are
+// made absolute from root, as the sandbox opens them.
+func newPathFormat(absolute bool, stripPrefix, separator string, root *sandbox) (*pathFormat, error) {
+	f := &pathFormat{absolute: absolute, separator: separator, root: root}
+	if len(separator) > 1 {
+		return nil, msgInvalidPathSeparator.errorf(separator)
+	}
+	if stripPrefix != "" {
+		dir, err := filepath.Abs(stripPrefix)
+		if err != nil {
+			return nil, err
+		}
+		f.stripPrefix = dir
+	}
+	return f, nil
+}
+
+// display returns path as it should be printed.
+func (f *pathFormat) display(path string) string {
+	if f == nil || path == stdinName {
+		return path
+	}
+	if path == f.lastPath && f.lastDisplay != "" {
+		return f.lastDisplay
+	}
+
+	shown := path
+	if f.absolute || f.stripPrefix != "" {
+		if abs, err := filepath.Abs(f.root.resolve(path)); err == nil {
+			if f.absolute {
+				shown = abs
+			}
+			if rel, ok := cutDir(abs, f.stripPrefix); ok {
+				shown = rel
+			}
+		}
+	}
+	if f.separator != "" && f.separator != string(filepath.Separator) {
+		shown = strings.ReplaceAll(shown, string(filepath.Separator), f.separator)
+	}
+
+	f.lastPath, f.lastDisplay = path, shown
+	return shown
+}
+
+// cutDir returns path relative to dir if path lies below dir.
+func cutDir(path, dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(path, dir)
+	if !ok {
+		return "", false
+	}
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return rest, rest != ""
+	}
+	rest, ok = strings.CutPrefix(rest, string(filepath.Separator))
+	return rest, ok && rest != ""
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SGR sequences used when colour is enabled, following ripgrep's defaults.
+const (
+	colorPath    = "\x1b[35m"
+	colorLineNum = "\x1b[32m"
+	colorMatch   = "\x1b[1;31m"
+	colorReset   = "\x1b[0m"
+)
+
+// printer writes matching lines in one of the output formats.
+type printer interface {
+	// printMatch writes one matching line. Write errors are sticky, so
+	// the error of the last write is returned.
+	printMatch(lm *lineMatch) error
+	// printContext writes a non-matching line shown for -A, -B, -C or
+	// --passthru.
+	printContext(path string, c contextLine) error
+	// startFile is called before each file is searched, so that a file
+	// named twice is shown twice.
+	startFile()
+	flush() error
+}
+
+// printerConfig holds the options shared by all printers.
+type printerConfig struct {
+	withPath    bool             // show which file a line came from
+	lineNumbers bool             // show 1-based line numbers
+	color       bool             // colour paths, line numbers and matches
+	contextual  bool             // -A, -B or -C was given
+	null        bool             // terminate paths with NUL instead of ':' or '\n'
+	escapePaths bool             // escape control characters in paths
+	paths       *pathFormat      // how paths are shown
+	redact      *redactor        // masks sensitive text if set
+	column      bool             // show the column of the first match
+	columnUnit  columnUnit       // what columns and maxColumns count
+	maxColumns  int              // cut longer lines to this many columns if > 0
+	hyperlink   *hyperlinkFormat // wrap paths in OSC 8 links if set
+}
+
+// locatedPath returns path followed by the part of a document a line is
+// in: "notebook.ipynb:cell 7" for notebooks, "plan.xlsx[Budget!B3]" for
+// other documents.
+func locatedPath(path, location string) string {
+	switch {
+	case location == "":
+		return path
+	case isNotebook(path):
+		return path + ":" + location
+	}
+	return path + "[" + location + "]"
+}
+
+// writePath writes path with the location of a line in it. Hyperlinks go
+// to the file itself.
+func (c *printerConfig) writePath(w *bufio.Writer, path, location string, lineNum, column int) {
+	text := locatedPath(c.paths.display(path), location)
+	if c.escapePaths {
+		text = escapeControl(text)
+	}
+	if c.color {
+		text = colorPath + text + colorReset
+	}
+	if c.hyperlink != nil {
+		text = c.hyperlink.wrap(text, path, lineNum, column)
+	}
+	w.WriteString(text)
+}
+
+// writeLineNum writes the line number and sep if they are shown. Lines
+// of notebook cells always show theirs, as "line N".
+func (c *printerConfig) writeLineNum(w *bufio.Writer, path, location string, lineNum int, sep byte) {
+	notebook := location != "" && isNotebook(path)
+	if !c.lineNumbers && !notebook {
+		return
+	}
+	if notebook {
+		w.WriteString("line ")
+	}
+	if c.color {
+		w.WriteString(colorLineNum)
+	}
+	w.WriteString(strconv.Itoa(lineNum))
+	if c.color {
+		w.WriteString(colorReset)
+	}
+	w.WriteByte(sep)
+}
+
+// matchColumn returns the 1-based column of the first match of lm.
+func (c *printerConfig) matchColumn(lm *lineMatch) int {
+	if len(lm.matches) == 0 {
+		return 1
+	}
+	return c.columnUnit.column(lm.line, lm.matches[0][0])
+}
+
+func (c *printerConfig) writeColumn(w *bufio.Writer, column int, sep byte) {
+	if c.column && sep == ':' {
+		w.WriteString(strconv.Itoa(column))
+		w.WriteByte(sep)
+	}
+}
+
+func (c *printerConfig) writeText(w *bufio.Writer, text string, matches [][2]int) {
+	text, matches = c.redact.apply(text, matches)
+	if c.maxColumns > 0 {
+		text, matches = c.columnUnit.truncate(text, matches, c.maxColumns)
+	}
+	if !c.color {
+		w.WriteString(text)
+		return
+	}
+	pos := 0
+	for _, m := range matches {
+		w.WriteString(text[pos:m[0]])
+		w.WriteString(colorMatch)
+		w.WriteString(text[m[0]:m[1]])
+		w.WriteString(colorReset)
+		pos = m[1]
+	}
+	w.WriteString(text[pos:])
+}
+
+// writeDecoded notes what each encoded token that matched decodes to.
+func (c *printerConfig) writeDecoded(w *bufio.Writer, decoded []decodedMatch) {
+	for _, d := range decoded {
+		w.WriteByte(' ')
+		w.WriteString(msgDecoded.format(d.encoding, c.redact.redact(d.snippet)))
+	}
+}
+
+// pathTerminator returns what follows a path: NUL with --null, sep otherwise.
+func (c *printerConfig) pathTerminator(sep byte) byte {
+	if c.null {
+		return 0
+	}
+	return sep
+}
+
+// escapeControl escapes control characters so that a file name cannot move
+// the cursor or inject escape sequences on a terminal.
+func escapeControl(s string) string {
+	if !strings.ContainsFunc(s, unicode.IsControl) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// contextState tracks the lines a printer has written for the current file,
+// so that overlapping context is written once and skipped lines are marked
+// with a separator.
+type contextState struct {
+	started  bool
+	newFile  bool // startFile was called since the last line
+	path     string
+	location string
+	last     int // last line number written
+}
+
+// startFile makes the next line start a new file, even if its path is the
+// same as the last one.
+func (s *contextState) startFile() {
+	s.newFile = true
+}
+
+// next reports whether line lineNum of path, in the given part of a
+// document, still has to be written, and calls separator first if lines
+// were skipped since the last one.
+func (s *contextState) next(path, location string, lineNum int, separator func(newFile bool)) bool {
+	sameFile := s.started && !s.newFile && s.path == path
+	samePart := sameFile && s.location == location
+	if samePart && lineNum <= s.last {
+		return false
+	}
+	if s.started && (!samePart || lineNum > s.last+1) {
+		separator(!sameFile)
+	}
+	s.started, s.newFile, s.path, s.location, s.last = true, false, path, location, lineNum
+	return true
+}
+
+// standardPrinter writes grep's "path:line:text" format, using '-' instead
+// of ':' on context lines.
+type standardPrinter struct {
+	printerConfig
+	w   *bufio.Writer
+	ctx contextState
+}
+
+func newStandardPrinter(w io.Writer, cfg printerConfig) *standardPrinter {
+	return &standardPrinter{printerConfig: cfg, w: bufio.NewWriter(w)}
+}
+
+func (p *standardPrinter) printMatch(lm *lineMatch) error {
+	for _, c := range lm.before {
+		p.printContext(lm.path, c)
+	}
+	p.ctx.next(lm.path, lm.location, lm.lineNum, p.writeSeparator)
+	p.writeLine(lm.path, lm.location, lm.lineNum, p.matchColumn(lm), ':', lm.line, lm.matches)
+	p.writeDecoded(p.w, lm.decoded)
+	return p.w.WriteByte('\n')
+}
+
+func (p *standardPrinter) writeLine(path, location string, lineNum, column int, sep byte, text string, matches [][2]int) {
+	if p.withPath {
+		p.writePath(p.w, path, location, lineNum, column)
+		p.w.WriteByte(p.pathTerminator(sep))
+	}
+	p.writeLineNum(p.w, path, location, lineNum, sep)
+	p.writeColumn(p.w, column, sep)
+	p.writeText(p.w, text, matches)
+}
+
+func (p *standardPrinter) printContext(path string, c contextLine) error {
+	if !p.ctx.next(path, c.location, c.lineNum, p.writeSeparator) {
+		return nil
+	}
+	p.writeLine(path, c.location, c.lineNum, 1, '-', c.text, nil)
+	return p.w.WriteByte('\n')
+}
+
+func (p *standardPrinter) writeSeparator(newFile bool) {
+	if p.contextual {
+		p.w.WriteString("--\n")
+	}
+}
+
+func (p *standardPrinter) startFile() {
+	p.ctx.startFile()
+}
+
+func (p *standardPrinter) flush() error {
+	return p.w.Flush()
+}
+
+// headingPrinter writes each file name once above its matches, with a blank
+// line between files.
+type headingPrinter struct {
+	printerConfig
+	w            *bufio.Writer
+	ctx          contextState
+	lastPath     string
+	lastLocation string
+	started      bool
+	headed       bool // the heading of the current file has been written
+}
+
+func newHeadingPrinter(w io.Writer, cfg printerConfig) *headingPrinter {
+	return &headingPrinter{printerConfig: cfg, w: bufio.NewWriter(w)}
+}
+
+func (p *headingPrinter) printMatch(lm *lineMatch) error {
+	for _, c := range lm.before {
+		p.printContext(lm.path, c)
+	}
+	p.ctx.next(lm.path, lm.location, lm.lineNum, p.writeSeparator)
+	p.writeLine(lm.path, lm.location, lm.lineNum, p.matchColumn(lm), ':', lm.line, lm.matches)
+	p.writeDecoded(p.w, lm.decoded)
+	return p.w.WriteByte('\n')
+}
+
+func (p *headingPrinter) writeLine(path, location string, lineNum, column int, sep byte, text string, matches [][2]int) {
+	if p.withPath && (!p.headed || path != p.lastPath || location != p.lastLocation) {
+		if p.started {
+			p.w.WriteByte('\n')
+		}
+		p.writePath(p.w, path, location, lineNum, column)
+		p.w.WriteByte(p.pathTerminator('\n'))
+		p.lastPath, p.lastLocation, p.headed = path, location, true
+	}
+	p.started = true
+	p.writeLineNum(p.w, path, location, lineNum, sep)
+	p.writeColumn(p.w, column, sep)
+	p.writeText(p.w, text, matches)
+}
+
+func (p *headingPrinter) printContext(path string, c contextLine) error {
+	if !p.ctx.next(path, c.location, c.lineNum, p.writeSeparator) {
+		return nil
+	}
+	p.writeLine(path, c.location, c.lineNum, 1, '-', c.text, nil)
+	return p.w.WriteByte('\n')
+}
+
+// writeSeparator marks skipped lines within a file; files are already set
+// apart by their headings.
+func (p *headingPrinter) writeSeparator(newFile bool) {
+	if p.contextual && !(newFile && p.withPath) {
+		p.w.WriteString("--\n")
+	}
+}
+
+func (p *headingPrinter) startFile() {
+	p.ctx.startFile()
+	p.headed = false
+}
+
+func (p *headingPrinter) flush() error {
+	return p.w.Flush()
+}
+
+// pathPrinter writes only the names of files with matches, for -l.
+type pathPrinter struct {
+	printerConfig
+	w      *bufio.Writer
+	listed bool // the current file has been written
+}
+
+func newPathPrinter(w io.Writer, cfg printerConfig) *pathPrinter {
+	return &pathPrinter{printerConfig: cfg, w: bufio.NewWriter(w)}
+}
+
+func (p *pathPrinter) printMatch(lm *lineMatch) error {
+	if p.listed {
+		return nil
+	}
+	p.listed = true
+	p.writePath(p.w, lm.path, "", lm.lineNum, p.matchColumn(lm))
+	return p.w.WriteByte(p.pathTerminator('\n'))
+}
+
+func (p *pathPrinter) printContext(path string, c contextLine) error {
+	return nil
+}
+
+func (p *pathPrinter) startFile() {
+	p.listed = false
+}
+
+func (p *pathPrinter) flush() error {
+	return p.w.Flush()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactReplacement is written in place of redacted text.
+const redactReplacement = "[REDACTED]"
+
+// redactRule finds text to hide. valid, if set, rejects candidates the
+// regular expression is too loose to rule out, and parts, if set, returns
+// the ranges of a candidate to hide when the expression cannot tell where
+// they end. If group is set, only that submatch is a candidate, and the
+// rest of the match checks what surrounds it.
+type redactRule struct {
+	re    *regexp.Regexp
+	valid func(string) bool
+	parts func(string) [][2]int
+	group int
+}
+
+// find returns the byte ranges of text the rule hides.
+func (rule redactRule) find(text string) [][2]int {
+	var found [][2]int
+	if rule.group == 0 {
+		for _, loc := range rule.re.FindAllStringIndex(text, -1) {
+			found = append(found, [2]int{loc[0], loc[1]})
+		}
+	} else {
+		// Search again from the end of each submatch, so that the
+		// character after it can also come before the next one.
+		for pos := 0; pos < len(text); {
+			loc := rule.re.FindStringSubmatchIndex(text[pos:])
+			if loc == nil {
+				break
+			}
+			start, end := pos+loc[2*rule.group], pos+loc[2*rule.group+1]
+			found = append(found, [2]int{start, end})
+			pos = max(end, pos+1)
+		}
+	}
+	var kept [][2]int
+	for _, f := range found {
+		switch {
+		case f[0] == f[1]:
+		case rule.parts != nil:
+			for _, p := range rule.parts(text[f[0]:f[1]]) {
+				kept = append(kept, [2]int{f[0] + p[0], f[0] + p[1]})
+			}
+		case rule.valid == nil || rule.valid(text[f[0]:f[1]]):
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// redactPresets are the built-in --redact patterns.
+var redactPresets = map[string]redactRule{
+	"email":       {re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	"ipv4":        {re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\b`)},
+	"ipv6":        {re: regexp.MustCompile(`(?i)(?:^|[^0-9a-z:])([0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}(?:\.[0-9]{1,3}){0,3}(?:%[0-9a-z]+)?)(?:$|[^0-9a-z:])`), valid: validIPv6, group: 1},
+	"credit-card": {re: regexp.MustCompile(`\b[0-9](?:[ -]?[0-9]){12,18}\b`), parts: cardNumbers},
+}
+
+// validIPv6 accepts addresses of at least two groups, so that paths such
+// as std::vector, whose "d::" parses as an address, are left alone.
+func validIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is6() {
+		return false
+	}
+	groups := 0
+	for _, g := range strings.Split(strings.Split(s, "%")[0], ":") {
+		if g != "" {
+			groups++
+		}
+	}
+	return groups >= 2
+}
+
+// cardNumbers returns the card numbers in a run of digit groups, which may
+// also take in digits around the number, such as an expiry date after it.
+// From each group on, the longest run of whole groups with 13 to 19 digits
+// that passes the Luhn check is taken.
+func cardNumbers(s string) [][2]int {
+	var groups [][2]int
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j > i {
+			groups = append(groups, [2]int{i, j})
+			i = j
+		} else {
+			i++
+		}
+	}
+	var cards [][2]int
+	for i := 0; i < len(groups); i++ {
+		digits, end := 0, -1
+		for j := i; j < len(groups); j++ {
+			digits += groups[j][1] - groups[j][0]
+			if digits > 19 {
+				break
+			}
+			if digits >= 13 && luhnValid(s[groups[i][0]:groups[j][1]]) {
+				end = j
+			}
+		}
+		if end >= 0 {
+			cards = append(cards, [2]int{groups[i][0], groups[end][1]})
+			i = end
+		}
+	}
+	return cards
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by
+// payment card numbers.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// redactFlag collects repeated --redact values.
+type redactFlag []string
+
+func (f *redactFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *redactFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// redactor masks sensitive text in output while matching still uses the
+// original lines.
+type redactor struct {
+	rules []redactRule
+}
+
+// newRedactor builds a redactor from preset names and regular expressions
+// of at most sizeLimit instructions. It returns nil if there is nothing to
+// redact.
+func newRedactor(patterns []string, sizeLimit int) (*redactor, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	r := &redactor{}
+	for _, p := range patterns {
+		if preset, ok := redactPresets[p]; ok {
+			r.rules = append(r.rules, preset)
+			continue
+		}
+		re, err := compileLimited(p, sizeLimit)
+		if err != nil {
+			return nil, msgInvalidRedact.errorf(p, err)
+		}
+		r.rules = append(r.rules, redactRule{re: re})
+	}
+	return r, nil
+}
+
+// spans returns the sorted, merged byte ranges of text to hide.
+func (r *redactor) spans(text string) [][2]int {
+	var spans [][2]int
+	for _, rule := range r.rules {
+		spans = append(spans, rule.find(text)...)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s[0] <= last[1] {
+			last[1] = max(last[1], s[1])
+		} else {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// apply returns text with sensitive parts replaced, and matches moved to
+// the corresponding offsets. A match touching a redacted part covers the
+// whole replacement.
+func (r *redactor) apply(text string, matches [][2]int) (string, [][2]int) {
+	if r == nil {
+		return text, matches
+	}
+	spans := r.spans(text)
+	if len(spans) == 0 {
+		return text, matches
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, s := range spans {
+		b.WriteString(text[pos:s[0]])
+		b.WriteString(redactReplacement)
+		pos = s[1]
+	}
+	b.WriteString(text[pos:])
+
+	// mapOffset moves an offset in text to the redacted text. Offsets
+	// inside a span move to the start of its replacement, or to its end if
+	// they end a match.
+	mapOffset := func(off int, isEnd bool) int {
+		shift := 0
+		for _, s := range spans {
+			if off < s[0] || isEnd && off == s[0] {
+				return off + shift
+			}
+			if off < s[1] || isEnd && off == s[1] {
+				if isEnd {
+					return s[0] + shift + len(redactReplacement)
+				}
+				return s[0] + shift
+			}
+			shift += len(redactReplacement) - (s[1] - s[0])
+		}
+		return off + shift
+	}
+	moved := make([][2]int, 0, len(matches))
+	for _, m := range matches {
+		start, end := mapOffset(m[0], false), mapOffset(m[1], true)
+		if n := len(moved); n > 0 && start < moved[n-1][1] {
+			// Two matches inside the same replacement.
+			moved[n-1][1] = max(moved[n-1][1], end)
+			continue
+		}
+		moved = append(moved, [2]int{start, end})
+	}
+	return b.String(), moved
+}
+
+// redact returns text with sensitive parts replaced.
+func (r *redactor) redact(text string) string {
+	text, _ = r.apply(text, nil)
+	return text
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		preset, text, want string
+	}{
+		{"credit-card", "4111111111111111", "[REDACTED]"},
+		{"credit-card", "card 4111 1111 1111 1111", "card [REDACTED]"},
+		{"credit-card", "card 4111-1111-1111-1111 ok", "card [REDACTED] ok"},
+		{"credit-card", "card 4111 1111 1111 1111 12/25", "card [REDACTED] 12/25"},
+		{"credit-card", "card 4111 1111 1111 1111 12 25", "card [REDACTED] 12 25"},
+		{"credit-card", "ref 12 4111 1111 1111 1111", "ref 12 [REDACTED]"},
+		{"credit-card", "4111 1111 1111 1112", "4111 1111 1111 1112"},
+		{"credit-card", "on 2024 01 15 12 00 00", "on 2024 01 15 12 00 00"},
+		{"credit-card", "id 1234567890123", "id 1234567890123"},
+
+		{"ipv6", "from 2001:db8::1 to fe80::1%eth0", "from [REDACTED] to [REDACTED]"},
+		{"ipv6", "2001:db8::1,2001:db8::2", "[REDACTED],[REDACTED]"},
+		{"ipv6", "[2001:db8::7]:80", "[[REDACTED]]:80"},
+		{"ipv6", "::ffff:10.0.0.1", "[REDACTED]"},
+		{"ipv6", "std::vector<int> v", "std::vector<int> v"},
+		{"ipv6", "use std::io::Read;", "use std::io::Read;"},
+		{"ipv6", "at 12:30:45", "at 12:30:45"},
+
+		{"ipv4", "host 10.0.0.1:22", "host [REDACTED]:22"},
+		{"ipv4", "version 1.2.3.400", "version 1.2.3.400"},
+
+		{"email", "mail alice@example.com.", "mail [REDACTED]."},
+	}
+	for _, tt := range tests {
+		r, err := newRedactor([]string{tt.preset}, defaultRegexSizeLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.redact(tt.text); got != tt.want {
+			t.Errorf("%s: redact(%q) = %q, want %q", tt.preset, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactMovesMatches(t *testing.T) {
+	r, err := newRedactor([]string{"email"}, defaultRegexSizeLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, matches := r.apply("to bob@example.com: hi", [][2]int{{3, 6}, {20, 22}})
+	if text != "to [REDACTED]: hi" {
+		t.Fatalf("text = %q", text)
+	}
+	want := [][2]int{{3, 13}, {15, 17}}
+	if len(matches) != len(want) || matches[0] != want[0] || matches[1] != want[1] {
+		t.Errorf("matches = %v, want %v", matches, want)
+	}
+}
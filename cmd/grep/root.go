@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errOutsideRoot is returned for paths that lead outside --root.
+var errOutsideRoot = errors.New(msgOutsideRoot.String())
+
+// sandbox confines the files searched to the directory tree given by
+// --root, for searches driven by untrusted paths.
+type sandbox struct {
+	dir   string   // absolute, with symlinks resolved
+	given string   // absolute, as given
+	f     *os.File // dir itself, to open files beneath on Linux
+}
+
+func newSandbox(dir string) (*sandbox, error) {
+	given, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(given)
+	if err != nil {
+		return nil, msgInvalidRoot.errorf(err)
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, msgInvalidRoot.errorf(err)
+	}
+	if info, err := f.Stat(); err != nil || !info.IsDir() {
+		f.Close()
+		return nil, msgRootNotDir.errorf(dir)
+	}
+	return &sandbox{dir: resolved, given: given, f: f}, nil
+}
+
+// open opens path for reading. With a sandbox, relative paths are taken
+// from the root, and paths that leave it, directly or through a symlink,
+// are refused.
+func (s *sandbox) open(path string) (*os.File, error) {
+	if s == nil {
+		return os.Open(path)
+	}
+	rel, ok := s.rel(path)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path, Err: errOutsideRoot}
+	}
+	f, err := openBeneath(s, rel)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return f, nil
+}
+
+// resolve returns the path of the file open opens for path, taking
+// relative paths from the root. Without a sandbox it returns path.
+func (s *sandbox) resolve(path string) string {
+	if s == nil || filepath.IsAbs(path) || path == stdinName {
+		return path
+	}
+	return filepath.Join(s.given, path)
+}
+
+// readFile reads the whole of path through s.
+func readFile(s *sandbox, path string) ([]byte, error) {
+	f, err := s.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// rel returns path relative to the root, or false if it is lexically
+// outside it.
+func (s *sandbox) rel(path string) (string, bool) {
+	if filepath.IsAbs(path) {
+		for _, dir := range []string{s.dir, s.given} {
+			if rel, ok := within(dir, path); ok {
+				return rel, true
+			}
+		}
+		return "", false
+	}
+	return within(".", path)
+}
+
+// within returns target relative to dir if it does not climb out of dir.
+func within(dir, target string) (string, bool) {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// openChecked is the portable way to open rel beneath the root: resolve
+// symlinks, check the result, then make sure the file opened is the one
+// checked. A directory swapped for a symlink in between can still slip
+// through, which openat2 on Linux rules out.
+func openChecked(s *sandbox, rel string) (*os.File, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(s.dir, rel))
+	if err != nil {
+		// Do not reveal the absolute path of the root.
+		var pe *os.PathError
+		if errors.As(err, &pe) {
+			return nil, pe.Err
+		}
+		return nil, err
+	}
+	if _, ok := within(s.dir, resolved); !ok {
+		return nil, errOutsideRoot
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		var pe *os.PathError
+		if errors.As(err, &pe) {
+			return nil, pe.Err
+		}
+		return nil, err
+	}
+	opened, err := f.Stat()
+	if err == nil {
+		var checked os.FileInfo
+		checked, err = os.Lstat(resolved)
+		if err == nil && !os.SameFile(opened, checked) {
+			err = errOutsideRoot
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysOpenat2          = 437 // the same on every architecture
+	resolveNoMagiclinks = 0x02
+	resolveBeneath      = 0x08
+)
+
+// openHow is struct open_how from linux/openat2.h.
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// noOpenat2 is set once openat2 turns out to be unavailable.
+var noOpenat2 bool
+
+// openBeneath opens rel with openat2(2) and RESOLVE_BENEATH, so that the
+// kernel refuses ".." and symlinks that leave the root. Kernels before 5.6,
+// and seccomp filters that predate openat2, fall back to openChecked.
+func openBeneath(s *sandbox, rel string) (*os.File, error) {
+	if noOpenat2 {
+		return openChecked(s, rel)
+	}
+	p, err := syscall.BytePtrFromString(rel)
+	if err != nil {
+		return nil, err
+	}
+	how := openHow{
+		flags:   syscall.O_RDONLY | syscall.O_CLOEXEC,
+		resolve: resolveBeneath | resolveNoMagiclinks,
+	}
+	for {
+		fd, _, errno := syscall.Syscall6(sysOpenat2, s.f.Fd(),
+			uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+		switch errno {
+		case 0:
+			return os.NewFile(fd, rel), nil
+		case syscall.EINTR:
+			continue
+		case syscall.ENOSYS, syscall.EPERM:
+			noOpenat2 = true
+			return openChecked(s, rel)
+		case syscall.EXDEV:
+			return nil, errOutsideRoot
+		default:
+			return nil, errno
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// textTransform rewrites text before matching so that variants of the
+// same text compare equal. spans gives, for each byte of the result, the
+// range of bytes of s it came from; it is nil if s is unchanged.
+type textTransform interface {
+	apply(s string) (result string, spans [][2]int)
+}
+
+// matcher finds the search pattern in a line.
+type matcher struct {
+	pattern    string
+	ignoreCase bool
+	mode       textMode
+	transforms []textTransform // applied to lines, in order, before matching
+	decodings  []*decoding     // encoded tokens to match inside, for --decode
+	escapes    int             // \xNN escapes of invalid bytes in pattern
+}
+
+func newMatcher(pattern string, ignoreCase bool, mode textMode, transforms ...textTransform) *matcher {
+	pattern = mode.text(pattern)
+	for _, t := range transforms {
+		pattern, _ = t.apply(pattern)
+	}
+	m := &matcher{pattern: pattern, ignoreCase: ignoreCase, mode: mode, transforms: transforms}
+	if mode == modeUTF8 {
+		m.escapes = len(byteEscape.FindAllStringIndex(pattern, -1))
+	}
+	return m
+}
+
+// find returns the byte offsets of the first match in line, or -1, -1.
+func (m *matcher) find(line string) (int, int) {
+	if !m.ignoreCase {
+		i := strings.Index(line, m.pattern)
+		if i < 0 {
+			return -1, -1
+		}
+		return i, i + len(m.pattern)
+	}
+	if m.mode == modeBytes {
+		i := indexFoldASCII(line, m.pattern)
+		if i < 0 {
+			return -1, -1
+		}
+		return i, i + len(m.pattern)
+	}
+
+	// Compare rune by rune so that offsets refer to the original line even
+	// when folding changes the encoded length of a character.
+	n := utf8.RuneCountInString(m.pattern)
+	for i := 0; i < len(line); {
+		j, k := i, 0
+		for k < n && j < len(line) {
+			_, size := utf8.DecodeRuneInString(line[j:])
+			j += size
+			k++
+		}
+		if k < n {
+			break
+		}
+		if strings.EqualFold(line[i:j], m.pattern) {
+			return i, j
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+	}
+	return -1, -1
+}
+
+func (m *matcher) match(line string) bool {
+	start, _ := m.find(line)
+	return start >= 0
+}
+
+// findAll returns the byte offsets of all non-overlapping matches in line,
+// given where in line its \xNN escapes of invalid bytes start.
+func (m *matcher) findAll(line string, escapes []int) [][2]int {
+	text, spans := line, [][2]int(nil)
+	for _, t := range m.transforms {
+		next, nextSpans := t.apply(text)
+		if nextSpans == nil {
+			continue
+		}
+		if spans != nil {
+			// Chain back to the original line.
+			for i, s := range nextSpans {
+				nextSpans[i] = [2]int{spans[s[0]][0], spans[s[1]-1][1]}
+			}
+		}
+		text, spans = next, nextSpans
+	}
+	var matches [][2]int
+	for pos := 0; pos <= len(text); {
+		start, end := m.find(text[pos:])
+		if start < 0 {
+			break
+		}
+		start, end = pos+start, pos+end
+		mt := [2]int{start, end}
+		if spans != nil {
+			mt = originalOffsets([][2]int{mt}, spans, len(line))[0]
+		}
+		if !m.escapesMatch(mt, escapes) {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			pos = start + max(size, 1)
+			continue
+		}
+		matches = append(matches, [2]int{start, end})
+		if end == start {
+			end++ // empty pattern
+		}
+		pos = end
+	}
+	if spans != nil {
+		matches = originalOffsets(matches, spans, len(line))
+	}
+	return matches
+}
+
+// escapesMatch reports whether the escapes in line that match mt covers
+// are those of the pattern: the pattern's \xNN matches only an invalid
+// byte, not the same four characters of text, and nothing else matches
+// part of an escape.
+func (m *matcher) escapesMatch(mt [2]int, escapes []int) bool {
+	n := 0
+	for _, e := range escapes {
+		end := e + len(`\xff`)
+		if e < mt[0] && mt[0] < end || e < mt[1] && mt[1] < end {
+			return false
+		}
+		if mt[0] <= e && end <= mt[1] {
+			n++
+		}
+	}
+	return n == m.escapes
+}
+
+// originalOffsets moves matches in normalised text back to the bytes of
+// the original line they came from, merging any that now overlap.
+func originalOffsets(matches, spans [][2]int, n int) [][2]int {
+	var moved [][2]int
+	for _, mt := range matches {
+		start, end := n, 0
+		for _, s := range spans[mt[0]:mt[1]] {
+			start, end = min(start, s[0]), max(end, s[1])
+		}
+		if mt[0] == mt[1] { // empty pattern
+			if mt[0] < len(spans) {
+				start = spans[mt[0]][0]
+			}
+			end = start
+		}
+		if k := len(moved); k > 0 && start < moved[k-1][1] {
+			moved[k-1][1] = max(moved[k-1][1], end)
+			continue
+		}
+		moved = append(moved, [2]int{start, end})
+	}
+	return moved
+}
+
+// searchOptions controls how a file is scanned.
+type searchOptions struct {
+	before, after   int        // lines of context around each match
+	passthru        bool       // print non-matching lines as context
+	firstOnly       bool       // stop at the first matching line
+	maxMemory       int64      // bytes of lines held at once, or 0 for no limit
+	maxMemoryArg    string     // maxMemory as given, for error messages
+	root            *sandbox   // confines the files opened, if set
+	notebookOutputs bool       // also search the outputs of notebook cells
+	stringsMin      int        // search printable runs of this many characters, if > 0
+	hex             *hexSearch // search for a byte pattern instead, if set
+}
+
+// lineMatch is a matching line handed to the printers.
+type lineMatch struct {
+	path     string
+	location string // the part of a document the line is in, if any
+	lineNum  int    // 1-based
+	offset   int    // byte offset of the start of line within the file
+	escapes  []int  // where in line the \xNN escapes of invalid bytes start
+	line     string
+	matches  [][2]int       // byte offsets of each match within line
+	decoded  []decodedMatch // encoded tokens that matched once decoded
+	before   []contextLine  // up to -B lines preceding line
+	after    []contextLine  // up to -A lines following line
+}
+
+// contextLine is a line printed around a match for -A, -B and -C.
+type contextLine struct {
+	lineNum  int
+	text     string
+	location string // the part of a document the line is in, if any
+}
+
+// searchFile searches path, or standard input for "-", writing results to p.
+func searchFile(p printer, m *matcher, path string, opts *searchOptions) error {
+	if path == "-" {
+		if opts.hex != nil {
+			return opts.hex.search(stdinName, os.Stdin)
+		}
+		if opts.stringsMin > 0 {
+			return searchRuns(p, m, stdinName, "", 0, 0, os.Stdin, opts)
+		}
+		return searchReader(p, m, stdinName, "", os.Stdin, opts)
+	}
+	f, err := opts.root.open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if opts.hex != nil {
+		return opts.hex.search(path, f)
+	}
+	if opts.stringsMin > 0 {
+		return searchStrings(p, m, path, f, opts)
+	}
+	if isOfficeDocument(path) {
+		return searchDocument(p, m, path, f, opts)
+	}
+	if isNotebook(path) {
+		return searchNotebook(p, m, path, f, opts)
+	}
+	return searchReader(p, m, path, "", f, opts)
+}
+
+// windowLine is a line kept in memory for context.
+type windowLine struct {
+	contextLine
+	offset  int
+	size    int   // bytes read for the line, which held counts
+	escapes []int // where in text the \xNN escapes of invalid bytes start
+}
+
+// noBudget is the budget of readLine when there is no memory limit.
+const noBudget = int64(-1)
+
+// readLine reads up to and including the next newline, failing once the
+// line would take more than budget bytes, or noBudget for no limit.
+func readLine(br *bufio.Reader, budget int64) (string, error) {
+	if budget == noBudget {
+		return br.ReadString('\n')
+	}
+	if budget <= 0 {
+		return "", errOverBudget
+	}
+	var buf []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if int64(len(buf)+len(chunk)) > budget {
+			return "", errOverBudget
+		}
+		buf = append(buf, chunk...)
+		if err != bufio.ErrBufferFull {
+			return string(buf), err
+		}
+	}
+}
+
+// errOverBudget is returned by readLine when a line does not fit.
+var errOverBudget = errors.New("line over memory budget")
+
+// searchReader streams lines from r, keeping only the lines needed for
+// before- and after-context in memory.
+func searchReader(p printer, m *matcher, path, location string, r io.Reader, opts *searchOptions) error {
+	size := 64 * 1024
+	if opts.maxMemory > 0 {
+		size = int(min(int64(size), opts.maxMemory))
+	}
+	br := bufio.NewReaderSize(r, size)
+	var window []windowLine // before-context, the current line, then lookahead
+	var held int64          // bytes read for the lines in window
+	cur := 0                // index in window of the line to search next
+	lineNum, offset := 0, 0
+	afterLeft := 0
+	eof := false
+
+	for {
+		// Read far enough ahead to know the current line's after-context.
+		for !eof && len(window)-cur <= opts.after {
+			if br.Buffered() == 0 {
+				// About to block: show what we have so far, so that
+				// "tail -f log | mygrep --passthru" keeps up.
+				if err := p.flush(); err != nil {
+					return err
+				}
+			}
+			budget := noBudget
+			if opts.maxMemory > 0 {
+				budget = opts.maxMemory - held
+			}
+			text, err := readLine(br, budget)
+			if err == errOverBudget {
+				// Fail this file rather than the whole process.
+				return msgLineLimit.errorf(path, lineNum+1,
+					&limitError{flag: "max-memory", value: opts.maxMemoryArg})
+			}
+			if err == io.EOF {
+				eof = true
+				if text == "" {
+					break
+				}
+			} else if err != nil {
+				return err
+			}
+			lineNum++
+			line, escapes := m.mode.escape(strings.TrimSuffix(text, "\n"))
+			window = append(window, windowLine{contextLine{lineNum: lineNum, text: line, location: location}, offset, len(text), escapes})
+			offset += len(text)
+			held += int64(len(text))
+		}
+		if cur >= len(window) {
+			return nil
+		}
+
+		line := window[cur]
+		matches := m.findAll(line.text, line.escapes)
+		var decoded []decodedMatch
+		if len(m.decodings) > 0 {
+			decoded = m.findDecoded(line.text)
+			matches = withTokens(matches, decoded)
+		}
+		if len(matches) > 0 {
+			lm := &lineMatch{path: path, location: location, lineNum: line.lineNum, offset: line.offset, escapes: line.escapes, line: line.text, matches: matches, decoded: decoded}
+			for _, w := range window[max(0, cur-opts.before):cur] {
+				lm.before = append(lm.before, w.contextLine)
+			}
+			for _, w := range window[cur+1 : min(len(window), cur+1+opts.after)] {
+				lm.after = append(lm.after, w.contextLine)
+			}
+			if err := p.printMatch(lm); err != nil {
+				return err
+			}
+			if opts.firstOnly {
+				return nil
+			}
+			afterLeft = opts.after
+		} else if opts.passthru || afterLeft > 0 {
+			if err := p.printContext(path, line.contextLine); err != nil {
+				return err
+			}
+			afterLeft = max(0, afterLeft-1)
+		}
+
+		cur++
+		if drop := cur - opts.before; drop > 0 {
+			for _, w := range window[:drop] {
+				held -= int64(w.size)
+			}
+			window = window[drop:]
+			cur -= drop
+		}
+	}
+}
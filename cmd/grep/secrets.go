@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// secretRule detects one kind of credential.
+type secretRule struct {
+	id         string
+	re         *regexp.Regexp
+	minEntropy float64 // bits per character the secret must reach
+	showMatch  bool    // the match only marks a secret, e.g. a PEM header
+}
+
+// secretRules is the built-in rule pack. The first capture group, if any,
+// is the secret itself.
+var secretRules = []secretRule{
+	{id: "aws-access-key-id", re: regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`), minEntropy: 3.0},
+	{id: "aws-secret-access-key", re: regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|key).{0,20}?['"=:\s]+([A-Za-z0-9/+]{40})\b`), minEntropy: 4.0},
+	{id: "gcp-api-key", re: regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`), minEntropy: 3.5},
+	{id: "github-token", re: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36})\b`), minEntropy: 3.5},
+	{id: "github-fine-grained-pat", re: regexp.MustCompile(`\b(github_pat_[A-Za-z0-9_]{82})\b`), minEntropy: 3.5},
+	{id: "gitlab-token", re: regexp.MustCompile(`\b(glpat-[A-Za-z0-9_\-]{20})\b`), minEntropy: 3.0},
+	{id: "slack-token", re: regexp.MustCompile(`\b(xox[baprs]-[A-Za-z0-9-]{10,72})\b`), minEntropy: 3.0},
+	{id: "stripe-secret-key", re: regexp.MustCompile(`\b((?:sk|rk)_live_[A-Za-z0-9]{24,99})\b`), minEntropy: 3.5},
+	{id: "jwt", re: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), minEntropy: 3.0},
+	{id: "private-key", re: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`), showMatch: true},
+	{id: "generic-secret", re: regexp.MustCompile(`(?i)(?:secret|passw(?:or)?d|token|api[_-]?key)["']?\s*[:=]\s*["']([^"'\s]{12,})["']`), minEntropy: 3.5},
+}
+
+// allowMarker on a line suppresses findings on that line.
+const allowMarker = "mygrep:allow"
+
+// secretFinding is a secret found on a line.
+type secretFinding struct {
+	rule    *secretRule
+	column  int // 1-based byte column of the secret
+	value   string
+	entropy float64
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// maskSecret keeps just enough of a secret to recognise it: the first four
+// characters of a secret of twelve or more, each other character becoming
+// one '*'.
+func maskSecret(s string) string {
+	n := utf8.RuneCountInString(s)
+	keep := 0
+	if n >= 12 {
+		keep = 4
+	}
+	i := 0
+	for range keep {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i] + strings.Repeat("*", n-keep)
+}
+
+// findSecrets returns the findings on line that pass the entropy checks and
+// are not allow-listed. Rules are tried in order and a secret is reported
+// once, by the most specific rule.
+func findSecrets(line string, allow []*regexp.Regexp) []secretFinding {
+	if strings.Contains(line, allowMarker) {
+		return nil
+	}
+	var findings []secretFinding
+	var taken [][2]int
+	for i := range secretRules {
+		rule := &secretRules[i]
+		for _, loc := range rule.re.FindAllStringSubmatchIndex(line, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if overlaps(taken, start, end) {
+				continue
+			}
+			value := line[start:end]
+			entropy := shannonEntropy(value)
+			if entropy < rule.minEntropy || allowed(value, allow) {
+				continue
+			}
+			taken = append(taken, [2]int{start, end})
+			findings = append(findings, secretFinding{rule: rule, column: start + 1, value: value, entropy: entropy})
+		}
+	}
+	return findings
+}
+
+func overlaps(spans [][2]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+func allowed(value string, allow []*regexp.Regexp) bool {
+	for _, re := range allow {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAllowlist reads one regular expression per line; blank lines and lines
+// starting with '#' are ignored.
+func loadAllowlist(path string) ([]*regexp.Regexp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var allow []*regexp.Regexp
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		re, err := compileLimited(line, defaultRegexSizeLimit)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		allow = append(allow, re)
+	}
+	return allow, nil
+}
+
+func secretsUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Println(msgSecretsUsage)
+		fmt.Println()
+		fmt.Println(msgSecretsAbout.format(allowMarker))
+		fmt.Println()
+		fmt.Println(msgOptions)
+		printDefaults(fs)
+	}
+}
+
+// runSecrets implements "mygrep secrets" and returns the exit status: 1 if
+// a secret was found or a file could not be read.
+func runSecrets(args []string) int {
+	fs := flag.NewFlagSet("secrets", flag.ExitOnError)
+	allowlist := fs.String("allowlist", "", msgFlagAllowlist.String())
+	listRules := fs.Bool("rules", false, msgFlagRules.String())
+	root := fs.String("root", "", msgFlagSecretsRoot.String())
+	fs.Usage = secretsUsage(fs)
+	fs.Parse(args)
+
+	if *listRules {
+		for _, rule := range secretRules {
+			fmt.Printf("%-24s %s\n", rule.id, rule.re)
+		}
+		return 0
+	}
+
+	var allow []*regexp.Regexp
+	if *allowlist != "" {
+		var err error
+		allow, err = loadAllowlist(*allowlist)
+		if err != nil {
+			fmt.Println(msgError, err)
+			return 1
+		}
+	}
+
+	var sb *sandbox
+	if *root != "" {
+		var err error
+		sb, err = newSandbox(*root)
+		if err != nil {
+			fmt.Println(msgError, err)
+			return 1
+		}
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	status := 0
+	for _, file := range files {
+		found, err := scanSecrets(w, file, allow, sb)
+		if err != nil {
+			w.Flush()
+			fmt.Println(msgErrorReadingFile, err)
+			status = 1
+		}
+		if found {
+			status = 1
+		}
+	}
+	return status
+}
+
+// scanSecrets writes a line per finding in path and reports whether there
+// were any.
+func scanSecrets(w *bufio.Writer, path string, allow []*regexp.Regexp, root *sandbox) (bool, error) {
+	var r io.Reader = os.Stdin
+	if path == "-" {
+		path = stdinName
+	} else {
+		f, err := root.open(path)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	found := false
+	br := bufio.NewReaderSize(r, 64*1024)
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			for _, f := range findSecrets(strings.TrimSuffix(line, "\n"), allow) {
+				found = true
+				value := maskSecret(f.value)
+				if f.rule.showMatch {
+					value = f.value
+				}
+				// There is no -0 here, so paths are always escaped.
+				fmt.Fprintf(w, "%s:%d:%d: %s: %s", escapeControl(path), lineNum, f.column, f.rule.id, escapeControl(value))
+				if f.rule.minEntropy > 0 {
+					fmt.Fprintf(w, " (entropy %.2f)", f.entropy)
+				}
+				w.WriteByte('\n')
+			}
+		}
+		if err == io.EOF {
+			return found, nil
+		}
+		if err != nil {
+			return found, err
+		}
+	}
+}
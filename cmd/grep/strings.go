@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchStrings searches the printable runs in f, as strings(1) finds
+// them, one line per run. Like "strings -t x", each line starts with the
+// address of its run: for ELF files the virtual address, with the section
+// as the location, shown as in "prog[.rodata]", and for other files the
+// offset. Sections that are not loaded have no address, so the offset
+// within the section is shown instead.
+func searchStrings(p printer, m *matcher, name string, f *os.File, opts *searchOptions) error {
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return searchRuns(p, m, name, "", 0, 0, f, opts)
+	}
+	for _, s := range ef.Sections {
+		if s.Type == elf.SHT_NULL || s.Type == elf.SHT_NOBITS {
+			continue
+		}
+		if err := searchRuns(p, m, name, s.Name, s.Addr, int64(s.Offset), s.Open(), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchRuns searches the runs in r, which starts at address base and at
+// offset within the file.
+func searchRuns(p printer, m *matcher, name, section string, base uint64, offset int64, r io.Reader, opts *searchOptions) error {
+	// searchReader holds the before-context, the current line and the
+	// after-context, and its bufio.Reader may have read one run more.
+	keep := opts.before + opts.after + 2
+	rr := &runReader{br: bufio.NewReader(r), min: opts.stringsMin, first: 1, keep: keep}
+	sp := &stringsPrinter{printer: p, base: base, offset: offset, runs: rr}
+	return searchReader(sp, m, name, section, rr, opts)
+}
+
+// runReader reads the runs of at least min printable characters in br as
+// lines, remembering the offset at which each of the last keep started.
+type runReader struct {
+	br     *bufio.Reader
+	min    int
+	pos    int64   // offset of the next byte of br
+	starts []int64 // offset of each run kept
+	first  int     // line number of starts[0]
+	keep   int
+	out    []byte // the run being returned
+	eof    bool
+}
+
+// start returns the offset of the run read as line lineNum.
+func (rr *runReader) start(lineNum int) int64 {
+	return rr.starts[lineNum-rr.first]
+}
+
+func (rr *runReader) Read(p []byte) (int, error) {
+	for len(rr.out) == 0 {
+		if rr.eof {
+			return 0, io.EOF
+		}
+		rr.next()
+	}
+	n := copy(p, rr.out)
+	rr.out = rr.out[n:]
+	return n, nil
+}
+
+// next reads up to the end of the next run that is long enough.
+func (rr *runReader) next() {
+	var run []byte
+	var start int64
+	chars := 0
+	for {
+		r, size, err := rr.br.ReadRune()
+		if err != nil {
+			rr.eof = true
+			break
+		}
+		rr.pos += int64(size)
+		if r == '\t' || unicode.IsPrint(r) && !(r == utf8.RuneError && size == 1) {
+			if chars == 0 {
+				start = rr.pos - int64(size)
+			}
+			run = utf8.AppendRune(run, r)
+			chars++
+			continue
+		}
+		if chars >= rr.min {
+			break
+		}
+		run, chars = run[:0], 0
+	}
+	if chars >= rr.min {
+		if len(rr.starts) == rr.keep {
+			rr.starts = append(rr.starts[:0], rr.starts[1:]...)
+			rr.first++
+		}
+		rr.starts = append(rr.starts, start)
+		rr.out = append(run, '\n')
+	}
+}
+
+// stringsPrinter puts the address of its run in front of each line.
+type stringsPrinter struct {
+	printer
+	base   uint64 // address of the start of the section
+	offset int64  // offset of the start of the section within the file
+	runs   *runReader
+}
+
+func (sp *stringsPrinter) printMatch(lm *lineMatch) error {
+	addressed := *lm
+	prefix := sp.address(lm.lineNum)
+	addressed.line = prefix + lm.line
+	// Make offset+match the offset of the match within the file, as
+	// searchReader only knows where it is among the runs.
+	addressed.offset = int(sp.offset+sp.runs.start(lm.lineNum)) - len(prefix)
+	addressed.escapes = make([]int, len(lm.escapes))
+	for i, e := range lm.escapes {
+		addressed.escapes[i] = e + len(prefix)
+	}
+	addressed.matches = make([][2]int, len(lm.matches))
+	for i, mt := range lm.matches {
+		addressed.matches[i] = [2]int{mt[0] + len(prefix), mt[1] + len(prefix)}
+	}
+	addressed.decoded = make([]decodedMatch, len(lm.decoded))
+	for i, d := range lm.decoded {
+		d.token = [2]int{d.token[0] + len(prefix), d.token[1] + len(prefix)}
+		addressed.decoded[i] = d
+	}
+	addressed.before = sp.addressAll(lm.before)
+	addressed.after = sp.addressAll(lm.after)
+	return sp.printer.printMatch(&addressed)
+}
+
+func (sp *stringsPrinter) printContext(path string, c contextLine) error {
+	c.text = sp.address(c.lineNum) + c.text
+	return sp.printer.printContext(path, c)
+}
+
+func (sp *stringsPrinter) addressAll(lines []contextLine) []contextLine {
+	var out []contextLine
+	for _, c := range lines {
+		c.text = sp.address(c.lineNum) + c.text
+		out = append(out, c)
+	}
+	return out
+}
+
+// address returns the address of the run read as line lineNum.
+func (sp *stringsPrinter) address(lineNum int) string {
+	return fmt.Sprintf("%#x ", sp.base+uint64(sp.runs.start(lineNum)))
+}
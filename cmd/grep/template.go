@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// formatRecord is the value a --format template is executed with.
+type formatRecord struct {
+	Path       string
+	Location   string // the part of a document, e.g. "Budget!B3", if any
+	Line       int    // 1-based line number
+	Column     int    // 1-based column of the match, in --column-unit
+	ByteOffset int    // byte offset of the match within the file, or within the text of a document part
+	Text       string // the whole line
+	Match      string // the matched text
+	Decoded    string // with --decode, the decoded text around a match inside an encoded token
+	Before     []formatContext
+	After      []formatContext
+}
+
+// formatContext is a line of context in a formatRecord.
+type formatContext struct {
+	Line int
+	Text string
+}
+
+var formatFuncs = template.FuncMap{
+	"csv":   csvQuote,
+	"shell": shellQuote,
+	"json":  jsonQuote,
+}
+
+// csvQuote quotes s as a CSV field when needed, as encoding/csv would.
+func csvQuote(s string) string {
+	if s == "" || !strings.ContainsAny(s, "\",\r\n") && s[0] != ' ' && s[0] != '\t' {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func jsonQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// templatePrinter writes one line per match or per matching line using a
+// user template.
+type templatePrinter struct {
+	w        *bufio.Writer
+	tmpl     *template.Template
+	perMatch bool
+	paths    *pathFormat
+	redact   *redactor
+	unit     columnUnit
+}
+
+func newTemplatePrinter(w io.Writer, format string, perMatch bool, paths *pathFormat, redact *redactor, unit columnUnit) (*templatePrinter, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return nil, msgInvalidFormat.errorf(err)
+	}
+	// Catch references to unknown fields before any file is searched.
+	if err := tmpl.Execute(io.Discard, &formatRecord{}); err != nil {
+		return nil, msgInvalidFormat.errorf(err)
+	}
+	return &templatePrinter{w: bufio.NewWriter(w), tmpl: tmpl, perMatch: perMatch, paths: paths, redact: redact, unit: unit}, nil
+}
+
+func (p *templatePrinter) printMatch(lm *lineMatch) error {
+	matches := lm.matches
+	if !p.perMatch {
+		matches = matches[:1]
+	}
+	for _, m := range matches {
+		// Offsets refer to the original line; the text shown is redacted.
+		text, shown := p.redact.apply(lm.line, [][2]int{m})
+		rec := &formatRecord{
+			Path:       p.paths.display(lm.path),
+			Location:   lm.location,
+			Line:       lm.lineNum,
+			Column:     p.unit.column(lm.line, m[0]),
+			ByteOffset: lm.offset + rawOffset(lm.escapes, m[0]),
+			Text:       text,
+			Match:      text[shown[0][0]:shown[0][1]],
+			Before:     p.toFormatContext(lm.before),
+			After:      p.toFormatContext(lm.after),
+		}
+		for _, d := range lm.decoded {
+			if d.token[0] < m[1] && m[0] < d.token[1] {
+				rec.Decoded = p.redact.redact(d.snippet)
+			}
+		}
+		if err := p.tmpl.Execute(p.w, rec); err != nil {
+			return err
+		}
+		if err := p.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *templatePrinter) toFormatContext(lines []contextLine) []formatContext {
+	ctx := make([]formatContext, len(lines))
+	for i, c := range lines {
+		ctx[i] = formatContext{Line: c.lineNum, Text: p.redact.redact(c.text)}
+	}
+	return ctx
+}
+
+// printContext does nothing: context is available to the template through
+// .Before and .After.
+func (p *templatePrinter) printContext(path string, c contextLine) error {
+	return nil
+}
+
+func (p *templatePrinter) startFile() {}
+
+func (p *templatePrinter) flush() error {
+	return p.w.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplateUnknownField(t *testing.T) {
+	paths, _ := newPathFormat(false, "", "", nil)
+	if _, err := newTemplatePrinter(&bytes.Buffer{}, "{{.Groups}}", false, paths, nil, unitByte); err == nil {
+		t.Error("a template using .Groups was accepted")
+	}
+}
+
+func TestTemplateByteOffset(t *testing.T) {
+	data := "\x00\x01\x02abc\x00\x00hello world\xffx world\n"
+	tests := []struct {
+		name string
+		opts searchOptions
+		want string
+	}{
+		{"lines", searchOptions{}, "14 world\n22 world\n"},
+		{"strings", searchOptions{stringsMin: 4}, "14 world\n22 world\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		paths, _ := newPathFormat(false, "", "", nil)
+		p, err := newTemplatePrinter(&out, "{{.ByteOffset}} {{.Match}}", true, paths, nil, unitByte)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := newMatcher("world", false, modeUTF8)
+		if tt.opts.stringsMin > 0 {
+			err = searchRuns(p, m, "data", "", 0, 0, strings.NewReader(data), &tt.opts)
+		} else {
+			err = searchReader(p, m, "data", "", strings.NewReader(data), &tt.opts)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.flush()
+		if out.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, out.String(), tt.want)
+		}
+	}
+}
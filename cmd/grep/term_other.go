@@ -0,0 +1,31 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+type termState struct{}
+
+var errNoTerm = errors.New(msgNoTerm.format(runtime.GOOS))
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errNoTerm
+}
+
+func restoreTerm(fd int, st *termState) error {
+	return errNoTerm
+}
+
+func termSize(fd int) (rows, cols int, err error) {
+	return 0, 0, errNoTerm
+}
+
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// textMode is how --text-mode treats lines that are not valid UTF-8.
+type textMode int
+
+const (
+	// modeUTF8 reads each byte of invalid UTF-8 as the text \xNN, in the
+	// pattern as well as in lines, so that only a \xNN in the pattern can
+	// match one and the printers show it escaped. A \xNN in the pattern
+	// matches such a byte only, never the same four characters of text.
+	modeUTF8 textMode = iota
+	// modeBytes matches lines as raw bytes; -i folds ASCII letters only.
+	modeBytes
+)
+
+func parseTextMode(name string) (textMode, error) {
+	switch name {
+	case "utf8":
+		return modeUTF8, nil
+	case "bytes":
+		return modeBytes, nil
+	}
+	return 0, msgInvalidTextMode.errorf(name)
+}
+
+// text returns s as the matcher and printers see it.
+func (t textMode) text(s string) string {
+	text, _ := t.escape(s)
+	return text
+}
+
+// escape is text, also returning where in the text each \xNN escape of a
+// byte of s starts.
+func (t textMode) escape(s string) (string, []int) {
+	if t == modeBytes || utf8.ValidString(s) {
+		return s, nil
+	}
+	var b strings.Builder
+	var escapes []int
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			escapes = append(escapes, b.Len())
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String(), escapes
+}
+
+// byteEscape finds, in a pattern, the escapes escape writes for bytes of
+// invalid UTF-8, which are never ASCII.
+var byteEscape = regexp.MustCompile(`(?i)\\x[89a-f][0-9a-f]`)
+
+// rawOffset maps offset off in text escaped by escape back to the bytes
+// read, given where the escapes start. An offset inside an escape maps to
+// its byte.
+func rawOffset(escapes []int, off int) int {
+	raw := off
+	for _, e := range escapes {
+		if e >= off {
+			break
+		}
+		raw -= min(off-e, len(`\xff`)-1)
+	}
+	return raw
+}
+
+// indexFoldASCII is strings.Index ignoring the case of ASCII letters only,
+// so that bytes of invalid UTF-8 compare as themselves.
+func indexFoldASCII(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if equalFoldASCII(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalFoldASCII(a, b string) bool {
+	for i := 0; i < len(a); i++ {
+		if lowerASCII(a[i]) != lowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
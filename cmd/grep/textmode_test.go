@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	text, escapes := modeUTF8.escape("a\xffb\\xffc\xe3\x81")
+	if want := `a\xffb\xffc\xe3\x81`; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if want := []int{1, 11, 15}; !slices.Equal(escapes, want) {
+		t.Errorf("escapes = %v, want %v", escapes, want)
+	}
+	for off, want := range map[int]int{0: 0, 1: 1, 3: 1, 5: 2, 6: 3, 11: 8, 19: 10} {
+		if got := rawOffset(escapes, off); got != want {
+			t.Errorf("rawOffset(%d) = %d, want %d", off, got, want)
+		}
+	}
+
+	if text, escapes := modeBytes.escape("a\xffb"); text != "a\xffb" || escapes != nil {
+		t.Errorf("bytes mode: got %q, %v", text, escapes)
+	}
+}
+
+func TestMatcherEscapes(t *testing.T) {
+	tests := []struct {
+		mode    textMode
+		pattern string
+		line    string
+		want    [][2]int
+	}{
+		// A \xNN in the pattern matches an invalid byte, not the text.
+		{modeUTF8, `\xff`, "raw \xff byte", [][2]int{{4, 8}}},
+		{modeUTF8, "\xff", "raw \xff byte", [][2]int{{4, 8}}},
+		{modeUTF8, `\xff`, `text \xff`, nil},
+		{modeUTF8, `\xff`, "both \xff and \\xff", [][2]int{{5, 9}}},
+		{modeUTF8, `\xff`, "\\xff then \xff", [][2]int{{10, 14}}},
+		{modeUTF8, `a\xffb`, "a\xffb", [][2]int{{0, 6}}},
+		// Nothing else matches part of an escape.
+		{modeUTF8, "x", "\xff x", [][2]int{{5, 6}}},
+		{modeUTF8, "ff", "\xff", nil},
+		{modeUTF8, `\x`, `a\xffb`, [][2]int{{1, 3}}},
+		{modeBytes, "xff", "\xff \\xff", [][2]int{{3, 6}}},
+	}
+	for _, tt := range tests {
+		m := newMatcher(tt.pattern, false, tt.mode)
+		line, escapes := tt.mode.escape(tt.line)
+		got := m.findAll(line, escapes)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q in %q: got %v, want %v", tt.pattern, tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"unicode/utf8"
+)
+
+// tuiFile is a file loaded into memory for interactive searching.
+type tuiFile struct {
+	path    string
+	lines   []string
+	escapes [][]int // where in each line its \xNN escapes start
+}
+
+// tuiMatch is a single hit shown in the result list.
+type tuiMatch struct {
+	file       *tuiFile
+	line       int // index into file.lines
+	start, end int
+}
+
+type tuiResult struct {
+	gen     int
+	matches []tuiMatch
+}
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEnter
+	keyBackspace
+	keyClear
+	keyDeleteWord
+	keyQuit
+)
+
+type keyEvent struct {
+	kind keyKind
+	r    rune
+}
+
+// tuiState is everything the screen is drawn from.
+type tuiState struct {
+	query     []rune
+	matches   []tuiMatch
+	selected  int
+	offset    int
+	searching bool
+}
+
+// runTUI lets the user type a pattern and browse matches in paths live, then
+// opens $EDITOR at the chosen line.
+func runTUI(paths []string, ignoreCase bool, mode textMode, root *sandbox) error {
+	var files []*tuiFile
+	for _, path := range paths {
+		content, err := readFile(root, path)
+		if err != nil {
+			return msgTUIReadingFile.errorf(err)
+		}
+		f := &tuiFile{path: path}
+		for _, line := range strings.Split(string(content), "\n") {
+			text, escapes := mode.escape(line)
+			f.lines = append(f.lines, text)
+			f.escapes = append(f.escapes, escapes)
+		}
+		files = append(files, f)
+	}
+
+	picked, err := pickMatch(files, ignoreCase, mode)
+	if err != nil || picked == nil {
+		return err
+	}
+	return openEditor(picked.file.path, picked.line+1)
+}
+
+// pickMatch runs the interactive session on the controlling terminal and
+// returns the match chosen with Enter, or nil if the user quit.
+func pickMatch(files []*tuiFile, ignoreCase bool, mode textMode) (*tuiMatch, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the terminal also stops readKeys.
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	old, err := makeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer restoreTerm(fd, old)
+
+	// Switch to the alternate screen so the shell's scrollback is kept.
+	fmt.Fprint(tty, "\x1b[?1049h")
+	defer fmt.Fprint(tty, "\x1b[?1049l")
+
+	// done stops readKeys if it has keys left to send when we return.
+	done := make(chan struct{})
+	defer close(done)
+	keys := make(chan keyEvent)
+	go readKeys(tty, keys, done)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	defer signal.Stop(resize)
+
+	results := make(chan tuiResult)
+	ui := &tuiState{}
+	gen := 0
+	cancel := context.CancelFunc(func() {})
+	defer func() { cancel() }()
+
+	// search cancels the in-flight search and starts one for the current query.
+	search := func() {
+		cancel()
+		gen++
+		ui.selected, ui.offset = 0, 0
+		if len(ui.query) == 0 {
+			ui.matches, ui.searching = nil, false
+			return
+		}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		ui.searching = true
+		go func(gen int, m *matcher) {
+			matches := searchFiles(ctx, files, m)
+			select {
+			case results <- tuiResult{gen: gen, matches: matches}:
+			case <-ctx.Done():
+			}
+		}(gen, newMatcher(string(ui.query), ignoreCase, mode))
+	}
+
+	for {
+		ui.draw(tty, fd)
+
+		select {
+		case r := <-results:
+			if r.gen == gen {
+				ui.matches, ui.searching = r.matches, false
+			}
+		case <-resize:
+		case k, ok := <-keys:
+			if !ok {
+				return nil, nil
+			}
+			switch k.kind {
+			case keyRune:
+				ui.query = append(ui.query, k.r)
+				search()
+			case keyBackspace:
+				if len(ui.query) > 0 {
+					ui.query = ui.query[:len(ui.query)-1]
+					search()
+				}
+			case keyClear:
+				ui.query = ui.query[:0]
+				search()
+			case keyDeleteWord:
+				i := len(ui.query)
+				for i > 0 && ui.query[i-1] == ' ' {
+					i--
+				}
+				for i > 0 && ui.query[i-1] != ' ' {
+					i--
+				}
+				ui.query = ui.query[:i]
+				search()
+			case keyUp:
+				ui.move(-1)
+			case keyDown:
+				ui.move(1)
+			case keyPageUp:
+				ui.move(-ui.listHeight(fd))
+			case keyPageDown:
+				ui.move(ui.listHeight(fd))
+			case keyEnter:
+				if len(ui.matches) > 0 {
+					return &ui.matches[ui.selected], nil
+				}
+			case keyQuit:
+				return nil, nil
+			}
+		}
+	}
+}
+
+// searchFiles returns all matches of m in files, or nil if ctx is cancelled
+// before the search completes.
+func searchFiles(ctx context.Context, files []*tuiFile, m *matcher) []tuiMatch {
+	var matches []tuiMatch
+	for _, f := range files {
+		for i, line := range f.lines {
+			if i%1024 == 0 && ctx.Err() != nil {
+				return nil
+			}
+			if found := m.findAll(line, f.escapes[i]); len(found) > 0 {
+				matches = append(matches, tuiMatch{file: f, line: i, start: found[0][0], end: found[0][1]})
+			}
+		}
+	}
+	return matches
+}
+
+// readKeys decodes keystrokes from the terminal until it is closed or done
+// is closed.
+func readKeys(tty *os.File, keys chan<- keyEvent, done <-chan struct{}) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			select {
+			case keys <- k:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func parseKeys(b []byte) []keyEvent {
+	var keys []keyEvent
+	for len(b) > 0 {
+		switch {
+		case bytes.HasPrefix(b, []byte("\x1b[A")), bytes.HasPrefix(b, []byte("\x1bOA")):
+			keys, b = append(keys, keyEvent{kind: keyUp}), b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[B")), bytes.HasPrefix(b, []byte("\x1bOB")):
+			keys, b = append(keys, keyEvent{kind: keyDown}), b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[5~")):
+			keys, b = append(keys, keyEvent{kind: keyPageUp}), b[4:]
+		case bytes.HasPrefix(b, []byte("\x1b[6~")):
+			keys, b = append(keys, keyEvent{kind: keyPageDown}), b[4:]
+		case bytes.HasPrefix(b, []byte("\x1b[")):
+			// Ignore other escape sequences up to their final byte.
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			b = b[min(i+1, len(b)):]
+		case b[0] == 0x1b, b[0] == 0x03, b[0] == 0x04: // Esc, Ctrl-C, Ctrl-D
+			keys, b = append(keys, keyEvent{kind: keyQuit}), b[1:]
+		case b[0] == '\r', b[0] == '\n':
+			keys, b = append(keys, keyEvent{kind: keyEnter}), b[1:]
+		case b[0] == 0x7f, b[0] == 0x08:
+			keys, b = append(keys, keyEvent{kind: keyBackspace}), b[1:]
+		case b[0] == 0x10: // Ctrl-P
+			keys, b = append(keys, keyEvent{kind: keyUp}), b[1:]
+		case b[0] == 0x0e: // Ctrl-N
+			keys, b = append(keys, keyEvent{kind: keyDown}), b[1:]
+		case b[0] == 0x15: // Ctrl-U
+			keys, b = append(keys, keyEvent{kind: keyClear}), b[1:]
+		case b[0] == 0x17: // Ctrl-W
+			keys, b = append(keys, keyEvent{kind: keyDeleteWord}), b[1:]
+		case b[0] < 0x20:
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, keyEvent{kind: keyRune, r: r})
+			}
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+func (ui *tuiState) move(delta int) {
+	ui.selected = max(0, min(ui.selected+delta, len(ui.matches)-1))
+}
+
+// listHeight is the number of result rows; the preview pane gets the rest.
+func (ui *tuiState) listHeight(fd int) int {
+	rows, _, err := termSize(fd)
+	if err != nil || rows < 4 {
+		rows = 24
+	}
+	return (rows - 2) / 2
+}
+
+func (ui *tuiState) draw(tty *os.File, fd int) {
+	rows, cols, err := termSize(fd)
+	if err != nil || rows < 4 || cols < 10 {
+		rows, cols = 24, 80
+	}
+	listH := ui.listHeight(fd)
+	previewH := rows - 2 - listH
+
+	if ui.selected < ui.offset {
+		ui.offset = ui.selected
+	}
+	if ui.selected >= ui.offset+listH {
+		ui.offset = ui.selected - listH + 1
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H")
+	// Newlines go between rows so that the last row does not scroll.
+	drawn := 0
+	line := func(s string) {
+		if drawn > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(s)
+		buf.WriteString("\x1b[K")
+		drawn++
+	}
+
+	// Prompt with the match count right-aligned.
+	status := msgTUIMatches.format(len(ui.matches))
+	if ui.searching {
+		status = msgTUISearching.String()
+	}
+	prompt := clip("> "+sanitize(string(ui.query)), cols-displayWidth(status)-1)
+	line(prompt + strings.Repeat(" ", max(1, cols-displayWidth(prompt)-displayWidth(status))) + status)
+
+	for i := 0; i < listH; i++ {
+		n := ui.offset + i
+		if n >= len(ui.matches) {
+			line("")
+			continue
+		}
+		m := ui.matches[n]
+		text := fmt.Sprintf("%s:%d: %s", m.file.path, m.line+1, sanitize(m.file.lines[m.line]))
+		if n == ui.selected {
+			line("\x1b[7m" + clip(text, cols) + "\x1b[0m")
+		} else {
+			line(clip(text, cols))
+		}
+	}
+
+	if len(ui.matches) == 0 {
+		line(strings.Repeat("─", cols))
+		for i := 0; i < previewH; i++ {
+			line("")
+		}
+	} else {
+		m := ui.matches[ui.selected]
+		line(clip(fmt.Sprintf("── %s:%d ", m.file.path, m.line+1)+strings.Repeat("─", cols), cols))
+
+		first := max(0, m.line-previewH/2)
+		for i := 0; i < previewH; i++ {
+			n := first + i
+			if n >= len(m.file.lines) {
+				line("")
+				continue
+			}
+			gutter := fmt.Sprintf("%6d  ", n+1)
+			text := sanitize(m.file.lines[n])
+			if n == m.line {
+				line("\x1b[1m" + gutter + "\x1b[0m" + highlight(text, m.start, m.end, cols-len(gutter)))
+			} else {
+				line(gutter + clip(text, cols-len(gutter)))
+			}
+		}
+	}
+
+	// Leave the cursor at the end of the query.
+	fmt.Fprintf(&buf, "\x1b[1;%dH", min(cols, 3+displayWidth(string(ui.query))))
+	tty.Write(buf.Bytes())
+}
+
+// sanitize replaces control characters, C1 ones included, and bytes of
+// invalid UTF-8 with placeholders byte for byte, so that byte offsets into
+// the original text stay valid.
+func sanitize(s string) string {
+	b := []byte(s)
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		switch {
+		case r == '\t':
+			b[i] = ' '
+		case r < 0x20 || r >= 0x7f && r < 0xa0 || r == utf8.RuneError && size == 1:
+			for k := i; k < i+size; k++ {
+				b[k] = '?'
+			}
+		}
+		i += size
+	}
+	return string(b)
+}
+
+// clip truncates s to at most n terminal cells.
+func clip(s string, n int) string {
+	return s[:clipIndex(s, n)]
+}
+
+func clipIndex(s string, n int) int {
+	i, used := 0, 0
+	for i < len(s) {
+		size, w := unitDisplay.next(s[i:])
+		if used+w > n {
+			break
+		}
+		i += size
+		used += w
+	}
+	return i
+}
+
+// highlight clips s to n cells and colours the bytes from start to end.
+func highlight(s string, start, end, n int) string {
+	cut := clipIndex(s, n)
+	if start >= cut {
+		return s[:cut]
+	}
+	end = min(end, cut)
+	return s[:start] + "\x1b[1;31m" + s[start:end] + "\x1b[0m" + s[end:cut]
+}
+
+// openEditor opens path at line in $EDITOR (vi if unset).
+func openEditor(path string, line int) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+
+	// Run through the shell so that EDITOR may carry arguments, e.g. "code -w".
+	cmd := exec.Command("sh", "-c", editor+` "$1" "$2"`, "sh", fmt.Sprintf("+%d", line), path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	return cmd.Run()
+}
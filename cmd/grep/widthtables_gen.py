@@ -0,0 +1,69 @@
+#!/usr/bin/env python3
+"""Generate widthtables.go, the East Asian Width data for --column-unit.
+
+Run with `go generate ./cmd/grep`. The data comes from Python's unicodedata
+module, so the Unicode version follows the Python used to run this script.
+"""
+
+import sys
+import unicodedata
+
+
+# Unassigned code points in these blocks default to Wide (UAX #11). Some
+# Pythons report unassigned code points elsewhere as Fullwidth too, so those
+# are left out.
+DEFAULT_WIDE = [
+    (0x3400, 0x4DBF),
+    (0x4E00, 0x9FFF),
+    (0xF900, 0xFAFF),
+    (0x20000, 0x2FFFD),
+    (0x30000, 0x3FFFD),
+]
+
+
+def is_wide(cp):
+    c = chr(cp)
+    if unicodedata.category(c) == "Cn":
+        return any(lo <= cp <= hi for lo, hi in DEFAULT_WIDE)
+    return unicodedata.east_asian_width(c) in ("W", "F")
+
+
+def wide_ranges():
+    ranges, start = [], None
+    for cp in range(sys.maxunicode + 2):
+        wide = cp <= sys.maxunicode and is_wide(cp)
+        if wide and start is None:
+            start = cp
+        elif not wide and start is not None:
+            ranges.append((start, cp - 1))
+            start = None
+    return ranges
+
+
+def main():
+    ranges = wide_ranges()
+    r16 = [r for r in ranges if r[1] <= 0xFFFF]
+    r32 = [r for r in ranges if r[0] > 0xFFFF]
+    assert len(r16) + len(r32) == len(ranges)
+    with open("widthtables.go", "w") as f:
+        f.write("// Code generated by widthtables_gen.py; DO NOT EDIT.\n\n")
+        f.write("package main\n\n")
+        f.write('import "unicode"\n\n')
+        f.write("// widthUnicodeVersion is the Unicode version of the tables.\n")
+        f.write('const widthUnicodeVersion = "%s"\n\n' % unicodedata.unidata_version)
+        f.write("// eastAsianWide holds the characters whose East Asian Width is Wide or\n")
+        f.write("// Fullwidth, which terminals show in two columns.\n")
+        f.write("var eastAsianWide = &unicode.RangeTable{\n")
+        f.write("\tR16: []unicode.Range16{\n")
+        for lo, hi in r16:
+            f.write("\t\t{0x%04x, 0x%04x, 1},\n" % (lo, hi))
+        f.write("\t},\n")
+        f.write("\tR32: []unicode.Range32{\n")
+        for lo, hi in r32:
+            f.write("\t\t{0x%x, 0x%x, 1},\n" % (lo, hi))
+        f.write("\t},\n")
+        f.write("}\n")
+
+
+if __name__ == "__main__":
+    main()